@@ -0,0 +1,424 @@
+package dsyaml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+	"gopkg.in/yaml.v3"
+)
+
+type decoder struct {
+	format *Format
+	reader io.Reader
+	file   *value.SourceFile
+	yaml   *yaml.Decoder
+}
+
+// Decode decodes the next document, discarding it. Use DecodeFrom to get the
+// value.
+func (d *decoder) Decode(target value.Source) error {
+	_, err := d.DecodeFrom(target)
+	return err
+}
+
+// DecodeFrom decodes the next document. Nodes are given value.Span sources in
+// source if it is a *value.SourceFile, or otherwise in a file named after
+// source holding the content read.
+func (d *decoder) DecodeFrom(source value.Source) (value.Value, error) {
+	if d.yaml == nil {
+		data, err := io.ReadAll(d.reader)
+		if err != nil {
+			return nil, err
+		}
+		file, ok := source.(*value.SourceFile)
+		if !ok || !bytes.Equal(file.Content(), data) {
+			if source == nil {
+				source = value.UnknownSource
+			}
+			file = value.NewSourceFile(source.String(), data)
+		}
+		d.file = file
+		d.yaml = yaml.NewDecoder(bytes.NewReader(data))
+	}
+	var document yaml.Node
+	if err := d.yaml.Decode(&document); err != nil {
+		return nil, err
+	}
+	state := &decodeState{
+		format:   d.format,
+		file:     d.file,
+		anchors:  make(map[*yaml.Node]value.Value),
+		decoding: make(map[*yaml.Node]bool),
+	}
+	v, _, err := state.value(&document)
+	return v, err
+}
+
+type decodeState struct {
+	format   *Format
+	file     *value.SourceFile
+	anchors  map[*yaml.Node]value.Value
+	decoding map[*yaml.Node]bool
+}
+
+// ErrDecode reports a node which cannot be represented as a value.
+type ErrDecode struct {
+	Source value.Source
+	Inner  error
+}
+
+func (e *ErrDecode) Error() string {
+	return fmt.Sprintf("error decoding yaml at %s: %s", e.Source.String(), e.Inner)
+}
+
+func (e *ErrDecode) Unwrap() error {
+	return e.Inner
+}
+
+func (s *decodeState) fail(n *yaml.Node, format string, args ...any) error {
+	span, _ := s.span(n, 0)
+	return &ErrDecode{Source: span, Inner: fmt.Errorf(format, args...)}
+}
+
+// span returns the source of n, which ends at end if that is later than
+// where n starts, and the offset n starts at.
+func (s *decodeState) span(n *yaml.Node, end int) (*value.Span, int) {
+	start := s.file.Offset(value.Position{Line: n.Line, Column: n.Column})
+	return s.file.Span(start, max(start, end)), start
+}
+
+// scalarEnd returns the offset just after the scalar n, starting at start.
+func (s *decodeState) scalarEnd(n *yaml.Node, start int) int {
+	content := s.file.Content()
+	// a tag precedes the scalar
+	if n.Tag != "" && start < len(content) && content[start] == '!' {
+		if i := bytes.IndexAny(content[start:], " \t\n"); i >= 0 {
+			start += i + 1
+		}
+	}
+	if start >= len(content) {
+		return start
+	}
+	switch {
+	case n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0:
+		quote := content[start]
+		for i := start + 1; i < len(content); i++ {
+			switch {
+			case quote == '"' && content[i] == '\\':
+				i++
+			case content[i] == quote && quote == '\'' && i+1 < len(content) && content[i+1] == '\'':
+				i++
+			case content[i] == quote:
+				return i + 1
+			}
+		}
+		return len(content)
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return s.blockEnd(start)
+	}
+	// a plain scalar, whose words are separated in the file by spaces or line
+	// breaks which may have been folded
+	end := start
+	for _, word := range strings.Fields(n.Value) {
+		for end < len(content) && isSpace(content[end]) {
+			end++
+		}
+		if !bytes.HasPrefix(content[end:], []byte(word)) {
+			return start
+		}
+		end += len(word)
+	}
+	return end
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func indentOf(line []byte) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+// blockEnd returns the offset just after the content of the literal or
+// folded scalar whose indicator is at start. The content is the following
+// lines indented at least as far as the first, which must be indented
+// further than the line holding the indicator.
+func (s *decodeState) blockEnd(start int) int {
+	content := s.file.Content()
+	lineStart := bytes.LastIndexByte(content[:start], '\n') + 1
+	parentIndent := indentOf(content[lineStart:])
+	// the indicator may be followed by chomping and indentation indicators
+	end := start + 1
+	for end < len(content) && strings.IndexByte("+-0123456789", content[end]) >= 0 {
+		end++
+	}
+	next := bytes.IndexByte(content[start:], '\n')
+	if next < 0 {
+		return end
+	}
+	blockIndent := -1
+	for pos := start + next + 1; pos < len(content); {
+		lineEnd := len(content)
+		if i := bytes.IndexByte(content[pos:], '\n'); i >= 0 {
+			lineEnd = pos + i
+		}
+		line := content[pos:lineEnd]
+		if len(bytes.TrimSpace(line)) > 0 {
+			indent := indentOf(line)
+			if blockIndent < 0 {
+				if indent <= parentIndent {
+					break
+				}
+				blockIndent = indent
+			}
+			if indent < blockIndent {
+				break
+			}
+			end = pos + len(bytes.TrimRight(line, " \t\r"))
+		}
+		pos = lineEnd + 1
+	}
+	return end
+}
+
+// value decodes n, returning the offset it ends at.
+func (s *decodeState) value(n *yaml.Node) (value.Value, int, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			span, start := s.span(n, 0)
+			return value.NewNull(span), start, nil
+		}
+		return s.value(n.Content[0])
+	case yaml.AliasNode:
+		return s.alias(n)
+	}
+	if s.format.keepAnchors && n.Anchor != "" {
+		if v, ok := s.anchors[n]; ok {
+			_, start := s.span(n, 0)
+			return v, start, nil
+		}
+	}
+	if s.decoding[n] {
+		return nil, 0, s.fail(n, "anchor &%s refers to itself", n.Anchor)
+	}
+	s.decoding[n] = true
+	defer delete(s.decoding, n)
+
+	var v value.Value
+	var end int
+	var err error
+	switch n.Kind {
+	case yaml.ScalarNode:
+		v, end, err = s.scalar(n)
+	case yaml.MappingNode:
+		v, end, err = s.mapping(n)
+	case yaml.SequenceNode:
+		v, end, err = s.sequence(n)
+	default:
+		err = s.fail(n, "unexpected node kind %d", n.Kind)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if s.format.keepAnchors && n.Anchor != "" {
+		v, err = value.WithSource(v, &AnchorSource{Anchor: n.Anchor, Source: v.Source()})
+		if err != nil {
+			return nil, 0, err
+		}
+		s.anchors[n] = v
+	}
+	return v, end, nil
+}
+
+// alias returns the value of the anchored node n refers to. Unless anchors
+// are kept the value is a copy whose source records the alias.
+func (s *decodeState) alias(n *yaml.Node) (value.Value, int, error) {
+	if n.Alias == nil {
+		return nil, 0, s.fail(n, "unknown anchor %s", n.Value)
+	}
+	_, start := s.span(n, 0)
+	aliasSpan := s.file.Span(start, start+len(n.Value)+1)
+	v, _, err := s.value(n.Alias)
+	if err != nil {
+		return nil, 0, err
+	}
+	if s.format.keepAnchors {
+		return v, aliasSpan.End, nil
+	}
+	v, err = value.Derive(v, value.Via, aliasSpan)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, aliasSpan.End, nil
+}
+
+var plainDecimal = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+func (s *decodeState) scalar(n *yaml.Node) (value.Value, int, error) {
+	_, start := s.span(n, 0)
+	end := s.scalarEnd(n, start)
+	span := s.file.Span(start, end)
+	switch n.ShortTag() {
+	case "!!null":
+		return value.NewNull(span), end, nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, 0, &ErrDecode{Source: span, Inner: err}
+		}
+		return value.NewBool(b, span), end, nil
+	case "!!int":
+		text := strings.ReplaceAll(n.Value, "_", "")
+		if i, ok := new(big.Int).SetString(strings.TrimPrefix(text, "+"), 0); ok {
+			return value.NewBigInt(i, span), end, nil
+		}
+		return nil, 0, &ErrDecode{Source: span, Inner: fmt.Errorf("invalid integer %q", n.Value)}
+	case "!!float":
+		if plainDecimal.MatchString(n.Value) {
+			return value.NewNumber(n.Value, span), end, nil
+		}
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return nil, 0, &ErrDecode{Source: span, Inner: err}
+		}
+		return value.NewFloat(f, span), end, nil
+	case "!!timestamp":
+		var t time.Time
+		if err := n.Decode(&t); err != nil {
+			return nil, 0, &ErrDecode{Source: span, Inner: err}
+		}
+		return value.NewTimestamp(t, span), end, nil
+	case "!!binary":
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(n.Value), ""))
+		if err != nil {
+			return nil, 0, &ErrDecode{Source: span, Inner: err}
+		}
+		return value.NewBytes(data, span), end, nil
+	case "!!str":
+		return value.NewString(n.Value, span), end, nil
+	case DurationTag:
+		d, err := time.ParseDuration(n.Value)
+		if err != nil {
+			return nil, 0, &ErrDecode{Source: span, Inner: err}
+		}
+		return value.NewDuration(d, span), end, nil
+	}
+	if strings.HasPrefix(n.Tag, "!") && !strings.HasPrefix(n.Tag, "!!") {
+		return value.NewTaggedString(n.Value, n.Tag, span), end, nil
+	}
+	return nil, 0, &ErrDecode{Source: span, Inner: fmt.Errorf("unsupported tag %s", n.Tag)}
+}
+
+func (s *decodeState) sequence(n *yaml.Node) (value.Value, int, error) {
+	_, start := s.span(n, 0)
+	end := start + 1
+	var elements []value.Value
+	for _, child := range n.Content {
+		v, childEnd, err := s.value(child)
+		if err != nil {
+			return nil, 0, err
+		}
+		elements = append(elements, v)
+		end = max(end, childEnd)
+	}
+	if n.Style&yaml.FlowStyle != 0 {
+		end = s.closing(end, ']')
+	}
+	return value.NewArray(elements, s.file.Span(start, end)), end, nil
+}
+
+// closing returns the offset after the bracket closing a flow collection
+// whose content ends at end.
+func (s *decodeState) closing(end int, bracket byte) int {
+	if i := bytes.IndexByte(s.file.Content()[end:], bracket); i >= 0 {
+		return end + i + 1
+	}
+	return end
+}
+
+func isMergeKey(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Value == "<<" && (n.Tag == "" || n.Tag == "!!merge" || n.Tag == "tag:yaml.org,2002:merge") && n.Style == 0
+}
+
+func (s *decodeState) mapping(n *yaml.Node) (value.Value, int, error) {
+	_, start := s.span(n, 0)
+	end := start + 1
+	elements := make(map[string]value.Value)
+	var merges []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, child := n.Content[i], n.Content[i+1]
+		if isMergeKey(k) {
+			merges = append(merges, child)
+			continue
+		}
+		name, err := s.keyName(k)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, ok := elements[name]; ok {
+			return nil, 0, s.fail(k, "duplicate key %q", name)
+		}
+		v, childEnd, err := s.value(child)
+		if err != nil {
+			return nil, 0, err
+		}
+		elements[name] = v
+		end = max(end, childEnd)
+	}
+	// keys given explicitly take precedence over merged ones, and earlier
+	// merged maps over later ones
+	for _, merge := range merges {
+		sources := []*yaml.Node{merge}
+		if merge.Kind == yaml.SequenceNode {
+			sources = merge.Content
+		}
+		for _, source := range sources {
+			v, childEnd, err := s.value(source)
+			if err != nil {
+				return nil, 0, err
+			}
+			end = max(end, childEnd)
+			m, ok := v.(value.Map)
+			if !ok || v.Kind() != value.MapKind {
+				return nil, 0, s.fail(source, "merge key expects a map, but got %s", v.Kind())
+			}
+			err = m.ForEach(func(k key.Interface, child value.Value) error {
+				name := value.KeyName(k)
+				if _, ok := elements[name]; !ok {
+					elements[name] = child
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	if n.Style&yaml.FlowStyle != 0 {
+		end = s.closing(end, '}')
+	}
+	return value.NewMap(elements, s.file.Span(start, end)), end, nil
+}
+
+func (s *decodeState) keyName(k *yaml.Node) (string, error) {
+	if k.Kind == yaml.AliasNode && k.Alias != nil {
+		k = k.Alias
+	}
+	if k.Kind != yaml.ScalarNode {
+		return "", s.fail(k, "keys must be scalars")
+	}
+	return k.Value, nil
+}
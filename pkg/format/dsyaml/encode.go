@@ -0,0 +1,237 @@
+package dsyaml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+	"gopkg.in/yaml.v3"
+)
+
+type encoder struct {
+	format    *Format
+	writer    io.Writer
+	documents int
+}
+
+// Encode writes v as a YAML document, preceded by a document separator if it
+// is not the first.
+func (e *encoder) Encode(v value.Value) error {
+	state := &encodeState{
+		format:  e.format,
+		seen:    make(map[value.Value]int),
+		encoded: make(map[value.Value]*yaml.Node),
+		names:   make(map[string]bool),
+	}
+	if e.format.keepAnchors {
+		if err := state.count(v); err != nil {
+			return err
+		}
+	}
+	node, err := state.node(v)
+	if err != nil {
+		return err
+	}
+	if e.documents > 0 {
+		if _, err := io.WriteString(e.writer, "---\n"); err != nil {
+			return err
+		}
+	}
+	e.documents++
+	y := yaml.NewEncoder(e.writer)
+	y.SetIndent(e.format.indent)
+	if err := y.Encode(node); err != nil {
+		return err
+	}
+	return y.Close()
+}
+
+type encodeState struct {
+	format  *Format
+	seen    map[value.Value]int
+	encoded map[value.Value]*yaml.Node
+	names   map[string]bool
+}
+
+// shareable reports whether v may be emitted once with an anchor and then
+// as aliases. Scalars are only shared if they were anchored when decoded, as
+// merge keys share the scalars of the merged map.
+func shareable(v value.Value) bool {
+	if !reflect.TypeOf(v).Comparable() {
+		return false
+	}
+	if _, ok := v.Source().(*AnchorSource); ok {
+		return true
+	}
+	return v.Kind() == value.MapKind || v.Kind() == value.ArrayKind
+}
+
+// count records how often each shareable value appears.
+func (s *encodeState) count(v value.Value) error {
+	if shareable(v) {
+		s.seen[v]++
+		if s.seen[v] > 1 {
+			return nil
+		}
+	}
+	if c, ok := v.(value.Collection); ok {
+		return c.ForEach(func(k key.Interface, child value.Value) error {
+			return s.count(child)
+		})
+	}
+	return nil
+}
+
+func (s *encodeState) anchorName(v value.Value) string {
+	if source, ok := v.Source().(*AnchorSource); ok && !s.names[source.Anchor] {
+		s.names[source.Anchor] = true
+		return source.Anchor
+	}
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("anchor%d", i)
+		if !s.names[name] {
+			s.names[name] = true
+			return name
+		}
+	}
+}
+
+func (s *encodeState) node(v value.Value) (*yaml.Node, error) {
+	shared := s.seen[v] > 1
+	if shared {
+		if anchored, ok := s.encoded[v]; ok {
+			return &yaml.Node{Kind: yaml.AliasNode, Value: anchored.Anchor, Alias: anchored}, nil
+		}
+	}
+	n, err := s.newNode(v)
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		n.Anchor = s.anchorName(v)
+		s.encoded[v] = n
+	}
+	return n, nil
+}
+
+func scalar(tag, text string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: text}
+}
+
+func (s *encodeState) newNode(v value.Value) (*yaml.Node, error) {
+	switch v.Kind() {
+	case value.MapKind:
+		m, ok := v.(value.Map)
+		if !ok {
+			return nil, fmt.Errorf("expected map, but got %T", v)
+		}
+		type entry struct {
+			name  string
+			value value.Value
+		}
+		var entries []entry
+		err := m.ForEach(func(k key.Interface, child value.Value) error {
+			entries = append(entries, entry{value.KeyName(k), child})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].name < entries[j].name
+		})
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, e := range entries {
+			child, err := s.node(e.value)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, scalar("!!str", e.name), child)
+		}
+		return n, nil
+	case value.ArrayKind:
+		a, ok := v.(value.Array)
+		if !ok {
+			return nil, fmt.Errorf("expected array, but got %T", v)
+		}
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		err := a.ForEach(func(k key.Interface, element value.Value) error {
+			child, err := s.node(element)
+			if err != nil {
+				return err
+			}
+			n.Content = append(n.Content, child)
+			return nil
+		})
+		return n, err
+	case value.NullKind:
+		return scalar("!!null", "null"), nil
+	}
+	simple, ok := v.(value.Simple)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %s value %T", v.Kind(), v)
+	}
+	if tagged, ok := v.(value.Tagged); ok {
+		return scalar(tagged.Tag(), simple.String()), nil
+	}
+	switch v.Kind() {
+	case value.BoolKind:
+		return scalar("!!bool", simple.String()), nil
+	case value.NumberKind:
+		return numberNode(v.(value.Number))
+	case value.TimestampKind:
+		t, err := v.(value.Timestamp).Time()
+		if err != nil {
+			return nil, err
+		}
+		return scalar("!!timestamp", t.Format(time.RFC3339Nano)), nil
+	case value.DurationKind:
+		d, err := v.(value.Duration).Duration()
+		if err != nil {
+			return nil, err
+		}
+		return scalar(DurationTag, d.String()), nil
+	case value.BytesKind:
+		data, err := v.(value.Bytes).Bytes()
+		if err != nil {
+			return nil, err
+		}
+		return scalar("!!binary", base64.StdEncoding.EncodeToString(data)), nil
+	}
+	return scalar("!!str", simple.String()), nil
+}
+
+func numberNode(n value.Number) (*yaml.Node, error) {
+	switch value.NumberTypeOf(n) {
+	case value.IntegerNumber, value.BigIntegerNumber:
+		return scalar("!!int", n.String()), nil
+	case value.ComplexNumber:
+		// a string would not decode back to a number
+		return nil, fmt.Errorf("cannot encode complex number %s: YAML has no complex type", n.String())
+	}
+	if _, isDecimal := n.(value.Decimal); !isDecimal {
+		f, err := n.Float(64)
+		if err == nil {
+			switch {
+			case math.IsInf(f, 1):
+				return scalar("!!float", ".inf"), nil
+			case math.IsInf(f, -1):
+				return scalar("!!float", "-.inf"), nil
+			case math.IsNaN(f):
+				return scalar("!!float", ".nan"), nil
+			}
+		}
+	}
+	text := n.String()
+	if _, err := strconv.ParseFloat(text, 64); err != nil {
+		return nil, fmt.Errorf("cannot encode number %s", text)
+	}
+	return scalar("!!float", text), nil
+}
@@ -0,0 +1,119 @@
+// Package dsyaml implements the YAML format. Importing it registers the
+// format as "yaml" for .yaml and .yml files.
+//
+// Anchors, aliases and merge keys are resolved while decoding. With
+// KeepAnchors an alias decodes to the same value as its anchor, and anchored
+// nodes have an AnchorSource, so that re-encoding emits the anchors again.
+//
+// Timestamps, bytes and durations are encoded with the !!timestamp, !!binary
+// and !duration tags respectively, so that they decode to the same kinds.
+package dsyaml
+
+import (
+	"fmt"
+	"io"
+
+	dsformat "github.com/davidjspooner/dsvalue/pkg/format"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// DurationTag marks a scalar holding a duration in the form accepted by
+// time.ParseDuration. YAML has no standard tag for durations.
+const DurationTag = "!duration"
+
+func init() {
+	dsformat.Register("yaml", New(), ".yaml", ".yml")
+}
+
+type Format struct {
+	keepAnchors bool
+	indent      int
+}
+
+var _ dsformat.Interface = &Format{}
+
+func New() *Format {
+	return &Format{indent: 2}
+}
+
+// KeepAnchors makes aliases share identity with their anchored value when
+// decoding, and emits anchors for values which appear more than once when
+// encoding. Modifying a shared value changes it wherever it appears.
+func KeepAnchors() dsformat.FormatOption {
+	return func(f dsformat.Interface) error {
+		y, ok := f.(*Format)
+		if !ok {
+			return fmt.Errorf("KeepAnchors expected a yaml format, but got %T", f)
+		}
+		y.keepAnchors = true
+		return nil
+	}
+}
+
+// WithIndent sets the number of spaces nested collections are indented by.
+func WithIndent(spaces int) dsformat.FormatOption {
+	return func(f dsformat.Interface) error {
+		y, ok := f.(*Format)
+		if !ok {
+			return fmt.Errorf("WithIndent expected a yaml format, but got %T", f)
+		}
+		if spaces < 1 {
+			return fmt.Errorf("invalid indent: %d", spaces)
+		}
+		y.indent = spaces
+		return nil
+	}
+}
+
+func (f *Format) Description() string {
+	return "YAML"
+}
+
+func (f *Format) NewWithOptions(options ...dsformat.FormatOption) (dsformat.Interface, error) {
+	copy := *f
+	for _, option := range options {
+		if err := option(&copy); err != nil {
+			return nil, err
+		}
+	}
+	return &copy, nil
+}
+
+func (f *Format) withOptions(options []dsformat.FormatOption) (*Format, error) {
+	configured, err := f.NewWithOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+	return configured.(*Format), nil
+}
+
+func (f *Format) NewEncoder(writer io.Writer, options ...dsformat.FormatOption) (dsformat.Encoder, error) {
+	configured, err := f.withOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return &encoder{format: configured, writer: writer}, nil
+}
+
+func (f *Format) NewDecoder(reader io.Reader, options ...dsformat.FormatOption) (dsformat.Decoder, error) {
+	configured, err := f.withOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return &decoder{format: configured, reader: reader}, nil
+}
+
+// AnchorSource is the source of a value decoded from an anchored node when
+// anchors are kept.
+type AnchorSource struct {
+	Anchor string
+	Source value.Source
+}
+
+func (s *AnchorSource) String() string {
+	return fmt.Sprintf("%s (&%s)", s.Source.String(), s.Anchor)
+}
+
+func (s *AnchorSource) Unwrap() value.Source {
+	return s.Source
+}
@@ -0,0 +1,280 @@
+package dsyaml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	dsformat "github.com/davidjspooner/dsvalue/pkg/format"
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+const anchoredDocument = `base: &base
+  image: web
+  port: 80
+dev:
+  <<: *base
+  port: 8080
+prod:
+  <<: [*base, {replicas: 3}]
+copy: *base
+`
+
+func decode(t *testing.T, text string, options ...dsformat.FormatOption) value.Value {
+	t.Helper()
+	d, err := New().NewDecoder(strings.NewReader(text), options...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	v, err := d.(dsformat.ValueDecoder).DecodeFrom(value.NewSourceFile("test.yaml", []byte(text)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return v
+}
+
+func field(t *testing.T, v value.Value, p string) value.Value {
+	t.Helper()
+	compiled, err := path.CompilePath(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result, err := compiled.EvaluateFor(v)
+	if err != nil {
+		t.Fatalf("evaluating %s: %s", p, err)
+	}
+	return result
+}
+
+func text(t *testing.T, v value.Value, p string) string {
+	t.Helper()
+	simple, ok := field(t, v, p).(value.Simple)
+	if !ok {
+		t.Fatalf("expected simple value at %s", p)
+	}
+	return simple.String()
+}
+
+func TestDecodeMergeKeys(t *testing.T) {
+	v := decode(t, anchoredDocument)
+	tests := map[string]string{
+		".dev.image":     "web",
+		".dev.port":      "8080",
+		".prod.port":     "80",
+		".prod.replicas": "3",
+		".copy.port":     "80",
+	}
+	for p, expected := range tests {
+		if got := text(t, v, p); got != expected {
+			t.Errorf("%s: expected %q, got %q", p, expected, got)
+		}
+	}
+	if _, err := field(t, v, ".dev").(value.Map).Field(key.Value[string]{X: "<<"}); err == nil {
+		t.Errorf("expected merge key to be removed")
+	}
+	if field(t, v, ".copy") == field(t, v, ".base") {
+		t.Errorf("expected alias to be a copy without KeepAnchors")
+	}
+	source := field(t, v, ".copy.port").Source().String()
+	if !strings.Contains(source, "Ln=3,") {
+		t.Errorf("expected source at anchored node, got %s", source)
+	}
+}
+
+func TestDecodeScalars(t *testing.T) {
+	v := decode(t, `
+null: ~
+bool: yes
+hex: 0x_ff
+float: 1.5
+inf: .inf
+time: 2001-12-14t21:59:43.10-05:00
+tagged: !custom value
+quoted: "true"
+wait: !duration 1m30s
+`)
+	kinds := map[string]value.Kind{
+		".null":   value.NullKind,
+		".hex":    value.NumberKind,
+		".float":  value.NumberKind,
+		".inf":    value.NumberKind,
+		".time":   value.TimestampKind,
+		".tagged": value.StringKind,
+		".quoted": value.StringKind,
+		".wait":   value.DurationKind,
+	}
+	for p, expected := range kinds {
+		if got := field(t, v, p).Kind(); got != expected {
+			t.Errorf("%s: expected kind %s, got %s", p, expected, got)
+		}
+	}
+	if got := text(t, v, ".hex"); got != "255" {
+		t.Errorf("expected 255, got %s", got)
+	}
+	tagged, ok := field(t, v, ".tagged").(value.Tagged)
+	if !ok || tagged.Tag() != "!custom" {
+		t.Errorf("expected !custom tag, got %v", field(t, v, ".tagged"))
+	}
+}
+
+func TestScalarSpans(t *testing.T) {
+	document := `script: |
+  echo one
+    echo two
+
+  echo three
+folded: >-
+  a
+  b
+plain: first
+  second
+tagged: !custom "quoted value"
+empty: |
+next: 1
+`
+	v := decode(t, document)
+	expected := map[string]string{
+		".script": "|\n  echo one\n    echo two\n\n  echo three",
+		".folded": ">-\n  a\n  b",
+		".plain":  "first\n  second",
+		".tagged": `!custom "quoted value"`,
+		".empty":  "|",
+		".next":   "1",
+	}
+	for p, text := range expected {
+		span, ok := field(t, v, p).Source().(*value.Span)
+		if !ok {
+			t.Fatalf("%s: expected a span, got %T", p, field(t, v, p).Source())
+		}
+		if got := document[span.Start:span.End]; got != text {
+			t.Errorf("%s: expected span %q, got %q", p, text, got)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := map[string]string{
+		"a: 1\na: 2\n":       "duplicate key",
+		"a: &x [1, *x]\n":    "",
+		"<<: [1]\n":          "merge",
+		"a: !!int nope\n":    "",
+		"a: !!binary '!!'\n": "",
+	}
+	for document, expected := range tests {
+		d, _ := New().NewDecoder(strings.NewReader(document))
+		_, err := d.(dsformat.ValueDecoder).DecodeFrom(value.NewSourceFile("bad.yaml", []byte(document)))
+		if err == nil {
+			t.Errorf("%q: expected error", document)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %s", document, expected, err)
+		}
+	}
+}
+
+func TestKeepAnchors(t *testing.T) {
+	v := decode(t, anchoredDocument, KeepAnchors())
+	base := field(t, v, ".base")
+	if field(t, v, ".copy") != base {
+		t.Fatalf("expected alias to share identity with its anchor")
+	}
+	source, ok := base.Source().(*AnchorSource)
+	if !ok || source.Anchor != "base" {
+		t.Fatalf("expected anchor source, got %s", base.Source())
+	}
+	excerpt := &bytes.Buffer{}
+	if err := value.RenderExcerpt(excerpt, source, "anchored"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(excerpt.String(), "image: web") {
+		t.Errorf("expected excerpt of anchored node, got %q", excerpt)
+	}
+
+	buf := &bytes.Buffer{}
+	e, err := New().NewEncoder(buf, KeepAnchors())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err = e.Encode(v); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	encoded := buf.String()
+	for _, expected := range []string{"base: &base", "copy: *base"} {
+		if !strings.Contains(encoded, expected) {
+			t.Errorf("expected %q in:\n%s", expected, encoded)
+		}
+	}
+	again := decode(t, encoded, KeepAnchors())
+	if field(t, again, ".copy") != field(t, again, ".base") {
+		t.Errorf("expected re-encoded alias to share identity")
+	}
+
+	differences := 0
+	err = path.Diff(v, again, func(p path.Path, left, right value.Value) error {
+		differences++
+		return nil
+	})
+	if err != nil || differences != 0 {
+		t.Errorf("expected no differences, got %d: %v", differences, err)
+	}
+}
+
+func TestDiffSharedSubtree(t *testing.T) {
+	left := decode(t, "a: &x {b: 1}\nc: *x\n", KeepAnchors())
+	shared := field(t, left, ".a")
+	right := value.NewMap(map[string]value.Value{
+		"a": shared,
+		"c": value.NewString("changed", nil),
+	}, nil)
+	var changed []string
+	err := path.Diff(left, right, func(p path.Path, l, r value.Value) error {
+		changed = append(changed, p.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(changed) != 1 || changed[0] != ".c" {
+		t.Errorf("expected only .c to differ, got %v", changed)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	v := decode(t, "s: 'true'\nn: 1.50\ni: 12\nlist: [a, ~]\n")
+	buf := &bytes.Buffer{}
+	e, _ := New().NewEncoder(buf)
+	if err := e.Encode(v); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := e.Encode(value.NewString("second", nil)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "i: 12\nlist:\n  - a\n  - null\nn: 1.50\ns: \"true\"\n---\nsecond\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	buf.Reset()
+	e, _ = New().NewEncoder(buf)
+	if err := e.Encode(value.NewDuration(90*time.Second, nil)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := field(t, decode(t, buf.String()), "."); got.Kind() != value.DurationKind {
+		t.Errorf("expected duration to survive a round trip, got %s from %q", got.Kind(), buf)
+	}
+
+	buf.Reset()
+	e, _ = New().NewEncoder(buf)
+	if err := e.Encode(value.NewComplex(1+2i, nil)); err == nil {
+		t.Errorf("expected an error encoding a complex number, but got %q", buf)
+	}
+
+	d, _ := dsformat.ForFile("x.yml")
+	if d == nil || d.Description() != "YAML" {
+		t.Errorf("expected yaml format to be registered for .yml")
+	}
+}
@@ -21,3 +21,12 @@ type Encoder interface {
 type Decoder interface {
 	Decode(target value.Source) error
 }
+
+// ValueDecoder is implemented by decoders which can return the documents
+// they decode.
+type ValueDecoder interface {
+	// DecodeFrom decodes the next document from the reader. Positions are
+	// attributed to source; if it is a *value.SourceFile holding the content
+	// being decoded, nodes are given value.Span sources.
+	DecodeFrom(source value.Source) (value.Value, error)
+}
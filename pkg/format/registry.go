@@ -0,0 +1,91 @@
+package dsformat
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+var registry = struct {
+	sync.RWMutex
+	byName      map[string]Interface
+	byExtension map[string]Interface
+}{
+	byName:      make(map[string]Interface),
+	byExtension: make(map[string]Interface),
+}
+
+// Register makes format available by name and for files with any of the
+// extensions, which include the leading dot. A later registration replaces an
+// earlier one.
+func Register(name string, format Interface, extensions ...string) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.byName[name] = format
+	for _, extension := range extensions {
+		registry.byExtension[strings.ToLower(extension)] = format
+	}
+}
+
+func Lookup(name string) (Interface, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	format, ok := registry.byName[name]
+	return format, ok
+}
+
+// Names returns the names of the registered formats in order.
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	var names []string
+	for name := range registry.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForFile returns the format registered for the extension of filename.
+func ForFile(filename string) (Interface, error) {
+	extension := strings.ToLower(filepath.Ext(filename))
+	registry.RLock()
+	defer registry.RUnlock()
+	format, ok := registry.byExtension[extension]
+	if !ok {
+		return nil, fmt.Errorf("no format registered for %q files", extension)
+	}
+	return format, nil
+}
+
+// DecodeFile decodes the first document in filename, using the format
+// registered for its extension. Nodes are given sources within the file.
+func DecodeFile(filename string) (value.Value, error) {
+	format, err := ForFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := format.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	valueDecoder, ok := decoder.(ValueDecoder)
+	if !ok {
+		return nil, fmt.Errorf("cannot decode %s: %s decoders do not return values", filename, format.Description())
+	}
+	v, err := valueDecoder.DecodeFrom(value.NewSourceFile(filename, data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", filename, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,276 @@
+// Package include assembles value trees split across files. A map whose only
+// key is $ref, such as {"$ref": "other.yaml#/spec"}, or a string tagged
+// !include, such as `!include other.yaml`, is replaced by the subtree it
+// refers to. The part after # is a JSON pointer; without a file name it refers
+// to the same file.
+package include
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	dsformat "github.com/davidjspooner/dsvalue/pkg/format"
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+const (
+	RefKey     = "$ref"
+	IncludeTag = "!include"
+)
+
+// Loader decodes the named file.
+type Loader func(filename string) (value.Value, error)
+
+type Option func(*Resolver) error
+
+// WithLoader replaces dsformat.DecodeFile as the way files are loaded.
+func WithLoader(loader Loader) Option {
+	return func(r *Resolver) error {
+		if loader == nil {
+			return fmt.Errorf("loader must not be nil")
+		}
+		r.loader = loader
+		return nil
+	}
+}
+
+// ErrInclude reports a reference which could not be resolved. Source is that
+// of the referencing node.
+type ErrInclude struct {
+	Reference string
+	Source    value.Source
+	Inner     error
+}
+
+func (e *ErrInclude) Error() string {
+	source := e.Source
+	if source == nil {
+		source = value.UnknownSource
+	}
+	return fmt.Sprintf("error including '%s' at %s: %s", e.Reference, source.String(), e.Inner)
+}
+
+func (e *ErrInclude) Unwrap() error {
+	return e.Inner
+}
+
+// ErrCycle reports references which include each other. Chain starts and ends
+// with the same reference.
+type ErrCycle struct {
+	Chain []string
+}
+
+func (e *ErrCycle) Error() string {
+	return fmt.Sprintf("include cycle: %s", strings.Join(e.Chain, " -> "))
+}
+
+// Resolver resolves references, caching each file it loads and each subtree
+// it resolves, so a Resolver may be reused for several related trees.
+type Resolver struct {
+	loader     Loader
+	files      map[string]value.Value
+	resolved   map[string]value.Value
+	inProgress []string
+}
+
+func NewResolver(options ...Option) (*Resolver, error) {
+	r := &Resolver{
+		loader:   dsformat.DecodeFile,
+		files:    make(map[string]value.Value),
+		resolved: make(map[string]value.Value),
+	}
+	for _, option := range options {
+		if err := option(r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// ResolveFile loads filename and resolves the references in it.
+func (r *Resolver) ResolveFile(filename string) (value.Value, error) {
+	absolute, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	root, err := r.load(absolute)
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveTarget(absolute, "", root)
+}
+
+// Resolve resolves the references in v, which was loaded from filename. File
+// names are relative to the directory containing filename. Included nodes
+// keep the sources they were given when their own file was decoded; the root
+// of each included subtree also records where it was included from.
+func (r *Resolver) Resolve(v value.Value, filename string) (value.Value, error) {
+	absolute, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := r.files[absolute]; !ok {
+		r.files[absolute] = v
+	}
+	return r.resolveTarget(absolute, "", v)
+}
+
+func (r *Resolver) load(filename string) (value.Value, error) {
+	if v, ok := r.files[filename]; ok {
+		return v, nil
+	}
+	v, err := r.loader(filename)
+	if err != nil {
+		return nil, err
+	}
+	r.files[filename] = v
+	return v, nil
+}
+
+// resolveTarget resolves v, found at pointer in filename, detecting cycles
+// through the references being followed.
+func (r *Resolver) resolveTarget(filename, pointer string, v value.Value) (value.Value, error) {
+	id := filename + "#" + pointer
+	if resolved, ok := r.resolved[id]; ok {
+		return resolved, nil
+	}
+	for i, pending := range r.inProgress {
+		if pending == id {
+			chain := append(append([]string(nil), r.inProgress[i:]...), id)
+			return nil, &ErrCycle{Chain: chain}
+		}
+	}
+	r.inProgress = append(r.inProgress, id)
+	defer func() {
+		r.inProgress = r.inProgress[:len(r.inProgress)-1]
+	}()
+	resolved, err := r.resolveTree(filename, v)
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[id] = resolved
+	return resolved, nil
+}
+
+// reference returns the reference v makes, if any. If v is a malformed
+// reference the text returned is the reference, or failing that the key or
+// tag marking it, for use in the error.
+func reference(v value.Value) (string, bool, error) {
+	if tagged, ok := v.(value.Tagged); ok && tagged.Tag() == IncludeTag {
+		s, ok := v.(value.Simple)
+		if !ok || v.Kind() != value.StringKind {
+			return IncludeTag, false, fmt.Errorf("%s expects a file name, but got %s", IncludeTag, v.Kind())
+		}
+		return s.String(), true, nil
+	}
+	m, ok := v.(value.Map)
+	if !ok || v.Kind() != value.MapKind {
+		return "", false, nil
+	}
+	ref, err := m.Field(key.Value[string]{X: RefKey})
+	if err != nil {
+		return "", false, nil
+	}
+	s, ok := ref.(value.Simple)
+	if !ok || ref.Kind() != value.StringKind {
+		return RefKey, false, fmt.Errorf("%s must be a string, but got %s", RefKey, ref.Kind())
+	}
+	length, err := m.Length()
+	if err != nil {
+		return s.String(), false, err
+	}
+	if length != 1 {
+		return s.String(), false, fmt.Errorf("%s must be the only key in its map", RefKey)
+	}
+	return s.String(), true, nil
+}
+
+func (r *Resolver) resolveTree(filename string, v value.Value) (value.Value, error) {
+	ref, ok, err := reference(v)
+	if err != nil {
+		return nil, &ErrInclude{Reference: ref, Source: v.Source(), Inner: err}
+	}
+	if ok {
+		return r.include(filename, ref, v)
+	}
+	switch v.Kind() {
+	case value.MapKind:
+		m, ok := v.(value.Map)
+		if !ok {
+			return nil, fmt.Errorf("expected map, but got %T", v)
+		}
+		elements := make(map[string]value.Value)
+		err := m.ForEach(func(k key.Interface, child value.Value) error {
+			resolved, err := r.resolveTree(filename, child)
+			if err != nil {
+				return err
+			}
+			elements[value.KeyName(k)] = resolved
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return value.NewMap(elements, v.Source()), nil
+	case value.ArrayKind:
+		a, ok := v.(value.Array)
+		if !ok {
+			return nil, fmt.Errorf("expected array, but got %T", v)
+		}
+		var elements []value.Value
+		err := a.ForEach(func(k key.Interface, child value.Value) error {
+			resolved, err := r.resolveTree(filename, child)
+			if err != nil {
+				return err
+			}
+			elements = append(elements, resolved)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return value.NewArray(elements, v.Source()), nil
+	}
+	return v, nil
+}
+
+// include returns the resolved subtree ref, made by site in filename, refers
+// to.
+func (r *Resolver) include(filename, ref string, site value.Value) (value.Value, error) {
+	fail := func(err error) error {
+		var includeErr *ErrInclude
+		if errors.As(err, &includeErr) {
+			return err
+		}
+		return &ErrInclude{Reference: ref, Source: site.Source(), Inner: err}
+	}
+	name, pointer, _ := strings.Cut(ref, "#")
+	target := filename
+	if name != "" {
+		target = filepath.FromSlash(name)
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(filename), target)
+		}
+	}
+	root, err := r.load(target)
+	if err != nil {
+		return nil, fail(err)
+	}
+	node, err := path.EvaluatePointer(root, pointer)
+	if err != nil {
+		return nil, fail(err)
+	}
+	resolved, err := r.resolveTarget(target, pointer, node)
+	if err != nil {
+		return nil, fail(err)
+	}
+	included, err := value.Derive(resolved, value.IncludedFrom, site.Source())
+	if err != nil {
+		return nil, fail(err)
+	}
+	return included, nil
+}
@@ -0,0 +1,144 @@
+package include
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	dsformat "github.com/davidjspooner/dsvalue/pkg/format"
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/reflected"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// testFormat decodes JSON without positions, which is enough to test includes.
+type testFormat struct{}
+
+func (f *testFormat) Description() string {
+	return "test JSON"
+}
+func (f *testFormat) NewWithOptions(options ...dsformat.FormatOption) (dsformat.Interface, error) {
+	return f, nil
+}
+func (f *testFormat) NewEncoder(writer io.Writer, options ...dsformat.FormatOption) (dsformat.Encoder, error) {
+	return nil, errors.ErrUnsupported
+}
+func (f *testFormat) NewDecoder(reader io.Reader, options ...dsformat.FormatOption) (dsformat.Decoder, error) {
+	return &testDecoder{reader}, nil
+}
+
+type testDecoder struct {
+	reader io.Reader
+}
+
+func (d *testDecoder) Decode(target value.Source) error {
+	_, err := d.DecodeFrom(target)
+	return err
+}
+
+func (d *testDecoder) DecodeFrom(source value.Source) (value.Value, error) {
+	var obj any
+	if err := json.NewDecoder(d.reader).Decode(&obj); err != nil {
+		return nil, err
+	}
+	return reflected.FromGo(obj, source)
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	return dir
+}
+
+func TestResolve(t *testing.T) {
+	dsformat.Register("test-json", &testFormat{}, ".json")
+	dir := writeFiles(t, map[string]string{
+		"main.json": `{
+  "service": {"$ref": "common.json#/service"},
+  "tags": {"$ref": "#/defaults/tags"},
+  "defaults": {"tags": ["a", "b"]}
+}`,
+		"common.json": `{"service": {"name": "web", "port": {"$ref": "port.json"}}}`,
+		"port.json":   `8080`,
+		"a.json":      `{"next": {"$ref": "b.json"}}`,
+		"b.json":      `{"next": {"$ref": "a.json#/next"}}`,
+	})
+	loads := 0
+	r, err := NewResolver(WithLoader(func(filename string) (value.Value, error) {
+		loads++
+		return dsformat.DecodeFile(filename)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resolved, err := r.ResolveFile(filepath.Join(dir, "main.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]any{
+		"service":  map[string]any{"name": "web", "port": "8080"},
+		"tags":     []any{"a", "b"},
+		"defaults": map[string]any{"tags": []any{"a", "b"}},
+	}
+	if !reflect.DeepEqual(resolved.WithoutSource(), expected) {
+		t.Errorf("expected %v, but got %v", expected, resolved.WithoutSource())
+	}
+	name, err := path.EvaluatePointer(resolved, "/service/name")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(name.Source().String(), "common.json") {
+		t.Errorf("expected the source in common.json, but got %s", name.Source())
+	}
+	service, _ := path.EvaluatePointer(resolved, "/service")
+	if !strings.Contains(service.Source().String(), "included from") {
+		t.Errorf("expected the source to record the include, but got %s", service.Source())
+	}
+
+	tagged := value.NewMap(map[string]value.Value{
+		"service": value.NewTaggedString("common.json#/service", IncludeTag, value.UnknownSource),
+	}, value.UnknownSource)
+	resolved, err = r.Resolve(tagged, filepath.Join(dir, "tagged.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := resolved.(value.Map).Field(key.Value[string]{X: "service"}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if loads != 3 {
+		t.Errorf("expected 3 files to be loaded once each, but got %d loads", loads)
+	}
+
+	_, err = r.ResolveFile(filepath.Join(dir, "a.json"))
+	var cycle *ErrCycle
+	if !errors.As(err, &cycle) {
+		t.Errorf("expected ErrCycle, but got %v", err)
+	}
+
+	malformed := map[string]value.Value{
+		"common.json": value.NewMap(map[string]value.Value{
+			RefKey:  value.NewString("common.json", value.UnknownSource),
+			"extra": value.NewString("x", value.UnknownSource),
+		}, value.UnknownSource),
+		RefKey: value.NewMap(map[string]value.Value{
+			RefKey: value.NewBool(true, value.UnknownSource),
+		}, value.UnknownSource),
+	}
+	for expected, v := range malformed {
+		_, err := r.Resolve(v, filepath.Join(dir, "malformed.json"))
+		var includeErr *ErrInclude
+		if !errors.As(err, &includeErr) || includeErr.Reference != expected {
+			t.Errorf("expected an error including %q, but got %v", expected, err)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+// Package interpolate resolves ${...} placeholders in the strings of a value
+// tree.
+//
+// A placeholder names an environment variable as ${env.HOME}, a value in the
+// same document by its path as ${.metadata.name}, or anything else as a
+// supplied variable, e.g. ${region}. ${name:-default} gives a default, used
+// when the name is unset or empty, which may itself contain placeholders.
+// $${ is a literal ${.
+package interpolate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+type Option func(*config) error
+
+type config struct {
+	vars   map[string]string
+	lookup func(string) (string, bool)
+}
+
+// WithVars supplies the variables placeholders without a prefix refer to.
+func WithVars(vars map[string]string) Option {
+	return func(c *config) error {
+		c.vars = vars
+		return nil
+	}
+}
+
+// WithEnv replaces os.LookupEnv as the source of ${env.NAME} values.
+func WithEnv(lookup func(string) (string, bool)) Option {
+	return func(c *config) error {
+		if lookup == nil {
+			return fmt.Errorf("environment lookup must not be nil")
+		}
+		c.lookup = lookup
+		return nil
+	}
+}
+
+// ErrInterpolation reports a placeholder which could not be resolved. Path
+// and Source locate the string containing it.
+type ErrInterpolation struct {
+	Path        path.Path
+	Source      value.Source
+	Placeholder string
+	Inner       error
+}
+
+func (e *ErrInterpolation) Error() string {
+	source := e.Source
+	if source == nil {
+		source = value.UnknownSource
+	}
+	return fmt.Sprintf("error interpolating '%s' in %s at %s: %s", e.Placeholder, e.Path.String(), source.String(), e.Inner)
+}
+
+func (e *ErrInterpolation) Unwrap() error {
+	return e.Inner
+}
+
+// ErrCycle reports document paths whose placeholders refer to each other.
+// Chain starts and ends with the same path.
+type ErrCycle struct {
+	Chain []string
+}
+
+func (e *ErrCycle) Error() string {
+	return fmt.Sprintf("reference cycle: %s", strings.Join(e.Chain, " -> "))
+}
+
+// Interpolate returns a copy of v with every placeholder resolved. A string
+// consisting of a single document path placeholder is replaced by the value
+// found there, whatever its kind; otherwise values are converted to strings.
+func Interpolate(v value.Value, options ...Option) (value.Value, error) {
+	c := &config{lookup: os.LookupEnv}
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, err
+		}
+	}
+	r := &resolver{
+		config:   c,
+		root:     v,
+		resolved: make(map[string]value.Value),
+	}
+	return r.resolve(path.Path{}, v)
+}
+
+type resolver struct {
+	*config
+	root       value.Value
+	resolved   map[string]value.Value
+	inProgress []string
+}
+
+func childPath(p path.Path, k key.Interface) path.Path {
+	child := make(path.Path, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, k)
+}
+
+// resolve returns the interpolated copy of v, found at p. Results are cached
+// by path, and paths being resolved are tracked to detect cycles.
+func (r *resolver) resolve(p path.Path, v value.Value) (value.Value, error) {
+	name := p.String()
+	if resolved, ok := r.resolved[name]; ok {
+		return resolved, nil
+	}
+	for i, pending := range r.inProgress {
+		if pending == name {
+			chain := append(append([]string(nil), r.inProgress[i:]...), name)
+			return nil, &ErrCycle{Chain: chain}
+		}
+	}
+	r.inProgress = append(r.inProgress, name)
+	defer func() {
+		r.inProgress = r.inProgress[:len(r.inProgress)-1]
+	}()
+
+	var resolved value.Value
+	var err error
+	switch v.Kind() {
+	case value.MapKind:
+		resolved, err = r.resolveMap(p, v)
+	case value.ArrayKind:
+		resolved, err = r.resolveArray(p, v)
+	case value.StringKind:
+		resolved, err = r.resolveString(p, v)
+	default:
+		resolved = v
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[name] = resolved
+	return resolved, nil
+}
+
+func (r *resolver) resolveMap(p path.Path, v value.Value) (value.Value, error) {
+	m, ok := v.(value.Map)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected map, but got %T", p.String(), v)
+	}
+	elements := make(map[string]value.Value)
+	err := m.ForEach(func(k key.Interface, child value.Value) error {
+		name := value.KeyName(k)
+		resolved, err := r.resolve(childPath(p, key.Value[string]{X: name}), child)
+		if err != nil {
+			return err
+		}
+		elements[name] = resolved
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.NewMap(elements, v.Source()), nil
+}
+
+func (r *resolver) resolveArray(p path.Path, v value.Value) (value.Value, error) {
+	a, ok := v.(value.Array)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected array, but got %T", p.String(), v)
+	}
+	var elements []value.Value
+	err := a.ForEach(func(k key.Interface, child value.Value) error {
+		resolved, err := r.resolve(childPath(p, k), child)
+		if err != nil {
+			return err
+		}
+		elements = append(elements, resolved)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.NewArray(elements, v.Source()), nil
+}
+
+func (r *resolver) resolveString(p path.Path, v value.Value) (value.Value, error) {
+	s, ok := v.(value.String)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected string, but got %T", p.String(), v)
+	}
+	text, err := s.StringOrError()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(text, "${") {
+		return v, nil
+	}
+	fail := func(placeholder string, err error) error {
+		if _, ok := err.(*ErrInterpolation); ok {
+			return err
+		}
+		return &ErrInterpolation{Path: p, Source: v.Source(), Placeholder: placeholder, Inner: err}
+	}
+	segments, err := parse(text)
+	if err != nil {
+		return nil, fail(text, err)
+	}
+	if len(segments) == 1 && segments[0].placeholder {
+		resolved, inserted, err := r.lookupValue(segments[0].text, v.Source())
+		if err != nil {
+			return nil, fail(segments[0].raw, err)
+		}
+		return value.WithSource(resolved, combine(inserted, v.Source()))
+	}
+	result, inserted, err := r.expand(segments, v.Source(), fail)
+	if err != nil {
+		return nil, err
+	}
+	return value.NewString(result, combine(inserted, v.Source())), nil
+}
+
+// combine returns the source of a string built by inserting values into the
+// string with source outer: the origins of each inserted value, followed by
+// outer.
+func combine(inserted []value.Value, outer value.Source) value.Source {
+	if len(inserted) == 0 {
+		return outer
+	}
+	p := value.ProvenanceOf(inserted[0].Source())
+	for _, v := range inserted[1:] {
+		p = p.Then(value.MergedWith, v.Source())
+	}
+	return p.Then(value.Via, outer)
+}
+
+// expand returns the text of segments, and the values inserted into it. The
+// placeholders are in a string with source outer.
+func (r *resolver) expand(segments []segment, outer value.Source, fail func(string, error) error) (string, []value.Value, error) {
+	sb := strings.Builder{}
+	var inserted []value.Value
+	for _, s := range segments {
+		if !s.placeholder {
+			sb.WriteString(s.text)
+			continue
+		}
+		resolved, from, err := r.lookupValue(s.text, outer)
+		if err != nil {
+			return "", nil, fail(s.raw, err)
+		}
+		simple, ok := resolved.(value.Simple)
+		if !ok {
+			return "", nil, fail(s.raw, fmt.Errorf("cannot insert a %s into a string", resolved.Kind()))
+		}
+		sb.WriteString(simple.String())
+		inserted = append(inserted, from...)
+	}
+	return sb.String(), inserted, nil
+}
+
+// lookupValue resolves the body of a placeholder, returning an error if the
+// name is unset and has no default. It also returns the values the result
+// came from: the value found, or any inserted into the default. Defaults are
+// attributed to outer, the source of the string containing the placeholder.
+func (r *resolver) lookupValue(body string, outer value.Source) (value.Value, []value.Value, error) {
+	name, def, hasDefault := splitDefault(body)
+	var found value.Value
+	switch {
+	case strings.HasPrefix(name, "env."):
+		if text, ok := r.lookup(strings.TrimPrefix(name, "env.")); ok {
+			found = value.NewString(text, &envSource{name: strings.TrimPrefix(name, "env.")})
+		}
+	case strings.HasPrefix(name, ".") || strings.HasPrefix(name, "["):
+		p, err := path.CompilePath(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		target, err := p.EvaluateFor(r.root)
+		if err == nil {
+			found, err = r.resolve(p, target)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	default:
+		if text, ok := r.vars[name]; ok {
+			found = value.NewString(text, &varSource{name: name})
+		}
+	}
+	if found != nil && !isEmpty(found) {
+		return found, []value.Value{found}, nil
+	}
+	if hasDefault {
+		segments, err := parse(def)
+		if err != nil {
+			return nil, nil, err
+		}
+		text, inserted, err := r.expand(segments, outer, func(placeholder string, err error) error {
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return value.NewString(text, combine(inserted, outer)), inserted, nil
+	}
+	if found != nil {
+		return found, []value.Value{found}, nil
+	}
+	return nil, nil, fmt.Errorf("%s is not set", name)
+}
+
+func isEmpty(v value.Value) bool {
+	if v.Kind() == value.NullKind {
+		return true
+	}
+	s, ok := v.(value.String)
+	if !ok || v.Kind() != value.StringKind {
+		return false
+	}
+	text, err := s.StringOrError()
+	return err == nil && text == ""
+}
+
+type envSource struct {
+	name string
+}
+
+func (s *envSource) String() string {
+	return "environment variable " + s.name
+}
+
+type varSource struct {
+	name string
+}
+
+func (s *varSource) String() string {
+	return "variable " + s.name
+}
+
+//-------------------------------------------
+
+type segment struct {
+	text        string
+	raw         string
+	placeholder bool
+}
+
+// parse splits s into literal text and placeholders, whose text is the body
+// between the braces.
+func parse(s string) ([]segment, error) {
+	var segments []segment
+	literal := strings.Builder{}
+	flush := func() {
+		if literal.Len() > 0 {
+			segments = append(segments, segment{text: literal.String()})
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			literal.WriteString("${")
+			i += 3
+		case strings.HasPrefix(s[i:], "${"):
+			end, err := matchBrace(s, i+2)
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			segments = append(segments, segment{text: s[i+2 : end], raw: s[i : end+1], placeholder: true})
+			i = end + 1
+		default:
+			literal.WriteByte(s[i])
+			i++
+		}
+	}
+	flush()
+	return segments, nil
+}
+
+// matchBrace returns the index of the brace closing the placeholder whose
+// body starts at start.
+func matchBrace(s string, start int) (int, error) {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			i += 2
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated placeholder in %q", s)
+}
+
+// splitDefault splits a placeholder body at the first :- which is not inside
+// a nested placeholder.
+func splitDefault(body string) (string, string, bool) {
+	depth := 0
+	for i := 0; i < len(body); i++ {
+		switch {
+		case strings.HasPrefix(body[i:], "${"):
+			depth++
+			i++
+		case body[i] == '}':
+			depth--
+		case depth == 0 && strings.HasPrefix(body[i:], ":-"):
+			return body[:i], body[i+2:], true
+		}
+	}
+	return body, "", false
+}
@@ -0,0 +1,127 @@
+package interpolate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/reflected"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+func mustFromGo(t *testing.T, obj any) value.Value {
+	v, err := reflected.FromGo(obj, value.UnknownSource)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return v
+}
+
+func TestInterpolate(t *testing.T) {
+	env := map[string]string{"HOME": "/home/app", "EMPTY": ""}
+	options := []Option{
+		WithVars(map[string]string{"region": "eu-west-1"}),
+		WithEnv(func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		}),
+	}
+	v := mustFromGo(t, map[string]any{
+		"metadata": map[string]any{"name": "web", "replicas": 3},
+		"home":     "${env.HOME}/data",
+		"host":     "${.metadata.name}.${region}.example.com",
+		"copies":   "${.metadata.replicas}",
+		"alias":    "${.host}",
+		"fallback": "${env.EMPTY:-${env.MISSING:-${.metadata.name}}}",
+		"escaped":  "$${env.HOME}",
+	})
+	result, err := Interpolate(v, options...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]any{
+		"metadata": map[string]any{"name": "web", "replicas": "3"},
+		"home":     "/home/app/data",
+		"host":     "web.eu-west-1.example.com",
+		"copies":   "3",
+		"alias":    "web.eu-west-1.example.com",
+		"fallback": "web",
+		"escaped":  "${env.HOME}",
+	}
+	actual := result.WithoutSource().(map[string]any)
+	for k, e := range expected {
+		if !reflect.DeepEqual(actual[k], e) {
+			t.Errorf("%s: expected %v, but got %v", k, e, actual[k])
+		}
+	}
+	copies, err := result.(value.Map).Field(key.Value[string]{X: "copies"})
+	if err != nil || copies.Kind() != value.NumberKind {
+		t.Errorf("expected copies to stay a number, but got %v %v", copies, err)
+	}
+
+	tests := []struct {
+		doc      map[string]any
+		contains string
+	}{
+		{map[string]any{"a": "${.b}", "b": "${.a}"}, "reference cycle"},
+		{map[string]any{"a": "${undefined}"}, "undefined is not set"},
+		{map[string]any{"a": "${.b"}, "unterminated placeholder"},
+		{map[string]any{"a": "x${.b}", "b": map[string]any{}}, "cannot insert a Map into a string"},
+	}
+	for _, test := range tests {
+		_, err := Interpolate(mustFromGo(t, test.doc), options...)
+		var interpolationErr *ErrInterpolation
+		if !errors.As(err, &interpolationErr) {
+			t.Errorf("%v: expected ErrInterpolation, but got %v", test.doc, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.contains) {
+			t.Errorf("%v: expected %q in %q", test.doc, test.contains, err)
+		}
+		if interpolationErr.Source == nil || interpolationErr.Source == value.UnknownSource {
+			t.Errorf("%v: expected the source of the string", test.doc)
+		}
+	}
+}
+
+type testSource string
+
+func (s testSource) String() string {
+	return string(s)
+}
+
+func TestProvenance(t *testing.T) {
+	v := value.NewMap(map[string]value.Value{
+		"name":     value.NewString("web", testSource("name.yaml")),
+		"home":     value.NewString("${env.HOME}", testSource("home.yaml")),
+		"host":     value.NewString("${.name}.${region}", testSource("host.yaml")),
+		"copy":     value.NewString("${.name}", testSource("copy.yaml")),
+		"fallback": value.NewString("${missing:-x}", testSource("fallback.yaml")),
+	}, testSource("doc.yaml"))
+	result, err := Interpolate(v,
+		WithVars(map[string]string{"region": "eu"}),
+		WithEnv(func(name string) (string, bool) {
+			return "/home/app", name == "HOME"
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]string{
+		"home":     "environment variable HOME via home.yaml",
+		"host":     "name.yaml merged with variable region via host.yaml",
+		"copy":     "name.yaml via copy.yaml",
+		"fallback": "fallback.yaml",
+	}
+	for name, source := range expected {
+		child, err := result.(value.Map).Field(key.Value[string]{X: name})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got := child.Source().String(); got != source {
+			t.Errorf("%s: expected source %q, but got %q", name, source, got)
+		}
+	}
+}
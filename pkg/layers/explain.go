@@ -0,0 +1,263 @@
+package layers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// Candidate is the value one layer has at an explained path.
+type Candidate struct {
+	Layer string
+	// Value is nil if the layer does not set the path.
+	Value    value.Value
+	Source   value.Source
+	Selected bool
+	Reason   string
+}
+
+// Explanation describes how the merged value at Path was chosen. Candidates
+// are in layer order. More than one candidate is selected when maps or arrays
+// are merged.
+type Explanation struct {
+	Path path.Path
+	// Value is the merged value, or nil if the path is not in the merged tree.
+	Value      value.Value
+	Candidates []Candidate
+}
+
+func (e *Explanation) String() string {
+	sb := strings.Builder{}
+	if e.Value == nil {
+		fmt.Fprintf(&sb, "%s is not set\n", e.Path.String())
+	} else {
+		fmt.Fprintf(&sb, "%s = %s\n", e.Path.String(), describe(e.Value))
+	}
+	for _, c := range e.Candidates {
+		marker := " "
+		if c.Selected {
+			marker = "*"
+		}
+		if c.Value == nil {
+			fmt.Fprintf(&sb, "%s %s: %s\n", marker, c.Layer, c.Reason)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s %s: %s from %s, %s\n", marker, c.Layer, describe(c.Value), c.Source.String(), c.Reason)
+	}
+	return sb.String()
+}
+
+func describe(v value.Value) string {
+	if s, ok := v.(value.Simple); ok {
+		return s.String()
+	}
+	return v.Kind().String()
+}
+
+// candidate tracks a layer's value while the explained path is followed.
+type candidate struct {
+	value value.Value
+	// active is false once the layer no longer contributes to the merged
+	// value, when reason says why.
+	active   bool
+	reason   string
+	selected bool
+}
+
+// Explain returns every layer's value at p and which of them the merged value
+// was taken from, so that precedence problems can be understood without
+// bisecting the layers by hand.
+func (l *Layers) Explain(p path.Path) (*Explanation, error) {
+	merged, err := l.Merge()
+	if err != nil {
+		return nil, err
+	}
+	e := &Explanation{Path: p}
+	if v, err := p.EvaluateFor(merged); err == nil {
+		e.Value = v
+	}
+
+	candidates := make([]candidate, len(l.layers))
+	for i, layer := range l.layers {
+		candidates[i] = candidate{value: layer.Value, active: layer.Value != nil}
+	}
+	prefix := path.Path{}
+	for depth := 0; ; depth++ {
+		final := depth == len(p)
+		effective := l.resolve(prefix, candidates, final)
+		if final {
+			l.selectCandidates(prefix, candidates, effective)
+			break
+		}
+		if err := l.descend(prefix, p[depth], candidates, effective); err != nil {
+			return nil, fmt.Errorf("cannot explain %s: %w", p.String(), err)
+		}
+		prefix = childPath(prefix, p[depth])
+	}
+
+	for i, c := range candidates {
+		reason := c.reason
+		if c.value == nil && reason == "" {
+			reason = "not set"
+		}
+		explained := Candidate{
+			Layer:    l.layers[i].Name,
+			Value:    c.value,
+			Selected: c.selected,
+			Reason:   reason,
+		}
+		if c.value != nil {
+			explained.Source = c.value.Source()
+		}
+		e.Candidates = append(e.Candidates, explained)
+	}
+	return e, nil
+}
+
+// resolve returns the indexes of the layers contributing to the merged value
+// at prefix, in the order they are merged. Layers which are overridden are
+// made inactive.
+func (l *Layers) resolve(prefix path.Path, candidates []candidate, final bool) []int {
+	var effective []int
+	for i := range candidates {
+		c := &candidates[i]
+		if !c.active {
+			continue
+		}
+		if len(effective) > 0 && l.combines(prefix, candidates[effective[0]].value, c.value) {
+			effective = append(effective, i)
+			continue
+		}
+		reason := "overridden by " + l.layers[i].Name
+		if c.value.Kind() == value.NullKind {
+			reason = "deleted by " + l.layers[i].Name
+		}
+		if !final {
+			reason += " at " + prefix.String()
+		}
+		for _, n := range effective {
+			candidates[n].active = false
+			candidates[n].reason = reason
+		}
+		effective = []int{i}
+	}
+	return effective
+}
+
+func (l *Layers) combines(prefix path.Path, base, over value.Value) bool {
+	switch {
+	case base.Kind() == value.MapKind && over.Kind() == value.MapKind:
+		return true
+	case base.Kind() == value.ArrayKind && over.Kind() == value.ArrayKind:
+		return l.strategyFor(prefix).Arrays != ReplaceArrays
+	}
+	return false
+}
+
+func (l *Layers) selectCandidates(prefix path.Path, candidates []candidate, effective []int) {
+	reason := "highest precedence layer setting the value"
+	if len(effective) > 1 {
+		reason = "merged as a map"
+		if candidates[effective[0]].value.Kind() == value.ArrayKind {
+			strategy := l.strategyFor(prefix)
+			reason = fmt.Sprintf("merged as an array (%s)", strategy.Arrays)
+			if strategy.Arrays == MergeArraysByKey {
+				reason = fmt.Sprintf("merged as an array (%s on %s)", strategy.Arrays, strategy.MergeKey)
+			}
+		}
+	} else if len(effective) == 1 && candidates[effective[0]].value.Kind() == value.NullKind {
+		reason = "deletes the value"
+	}
+	for _, n := range effective {
+		candidates[n].selected = true
+		candidates[n].reason = reason
+	}
+}
+
+// descend moves every candidate to its child at segment. Inactive candidates
+// are followed only to show what they would have set.
+func (l *Layers) descend(prefix path.Path, segment key.Interface, candidates []candidate, effective []int) error {
+	index, isIndex := segment.(key.Value[int])
+	if _, ok := segment.(key.Value[string]); !ok && !isIndex {
+		return fmt.Errorf("expected a field or index, but got %s", segment.String())
+	}
+	for i := range candidates {
+		c := &candidates[i]
+		if c.value == nil {
+			continue
+		}
+		if c.active && c.value.Kind() == value.NullKind {
+			c.active = false
+			c.reason = "deleted at " + prefix.String()
+		}
+		if isIndex && c.active && c.value.Kind() == value.ArrayKind {
+			// handled below, as the index depends on the strategy
+			continue
+		}
+		child, err := path.EvaluateFieldFor(c.value, segment)
+		if err != nil {
+			child = nil
+		}
+		c.value = child
+		if child == nil {
+			c.active = false
+		}
+	}
+
+	if !isIndex {
+		return nil
+	}
+	elements, err := l.replayArray(prefix, candidates, effective)
+	if err != nil {
+		return err
+	}
+	var slot map[int]value.Value
+	if index.X >= 0 && index.X < len(elements) {
+		slot = elements[index.X]
+	}
+	for _, n := range effective {
+		c := &candidates[n]
+		if !c.active || c.value.Kind() != value.ArrayKind {
+			continue
+		}
+		c.value = slot[n]
+		c.active = c.value != nil
+	}
+	return nil
+}
+
+// replayArray repeats the merge of the arrays at prefix, returning for each
+// element of the merged array the element each layer contributed to it.
+func (l *Layers) replayArray(prefix path.Path, candidates []candidate, effective []int) ([]map[int]value.Value, error) {
+	strategy := l.strategyFor(prefix)
+	var elements []map[int]value.Value
+	byKey := make(map[string]int)
+	first := true
+	for _, n := range effective {
+		a, ok := candidates[n].value.(value.Array)
+		if !ok || !candidates[n].active {
+			continue
+		}
+		err := a.ForEach(func(k key.Interface, child value.Value) error {
+			if strategy.Arrays == MergeArraysByKey {
+				if id, ok := elementKey(child, strategy.MergeKey); ok {
+					if existing, ok := byKey[id]; ok && !first {
+						elements[existing][n] = child
+						return nil
+					}
+					byKey[id] = len(elements)
+				}
+			}
+			elements = append(elements, map[int]value.Value{n: child})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		first = false
+	}
+	return elements, nil
+}
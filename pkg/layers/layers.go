@@ -0,0 +1,337 @@
+package layers
+
+import (
+	"fmt"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+type ArrayStrategy int
+
+const (
+	ReplaceArrays ArrayStrategy = iota
+	AppendArrays
+	MergeArraysByKey
+)
+
+func (s ArrayStrategy) String() string {
+	switch s {
+	case ReplaceArrays:
+		return "replace"
+	case AppendArrays:
+		return "append"
+	case MergeArraysByKey:
+		return "merge-by-key"
+	default:
+		return "unknown"
+	}
+}
+
+// Strategy controls how an array in a later layer is combined with the array
+// at the same path in the layers before it. Maps are always merged key by key
+// and anything else is replaced.
+type Strategy struct {
+	Arrays ArrayStrategy
+	// MergeKey names the field identifying elements when Arrays is
+	// MergeArraysByKey.
+	MergeKey string
+}
+
+type Layer struct {
+	Name  string
+	Value value.Value
+}
+
+// LayerSource is the source of a merged value, naming the layer it was taken
+// from.
+type LayerSource struct {
+	Layer  string
+	Source value.Source
+}
+
+func (s *LayerSource) String() string {
+	if s.Source == nil || s.Source == value.UnknownSource {
+		return fmt.Sprintf("layer %s", s.Layer)
+	}
+	return fmt.Sprintf("%s (layer %s)", s.Source.String(), s.Layer)
+}
+
+// Unwrap returns the source within the layer, so that value.RenderExcerpt
+// can show it.
+func (s *LayerSource) Unwrap() value.Source {
+	return s.Source
+}
+
+type pathStrategy struct {
+	pattern  path.Path
+	strategy Strategy
+}
+
+// Layers deep merges value trees, later layers taking precedence over earlier
+// ones. A null in a later layer deletes the key from the merged map.
+type Layers struct {
+	layers          []Layer
+	strategies      []pathStrategy
+	defaultStrategy Strategy
+}
+
+func New() *Layers {
+	return &Layers{}
+}
+
+func (l *Layers) Add(name string, v value.Value) *Layers {
+	l.layers = append(l.layers, Layer{Name: name, Value: v})
+	return l
+}
+
+func (l *Layers) Layers() []Layer {
+	return append([]Layer(nil), l.layers...)
+}
+
+// SetStrategy sets the strategy for arrays found at pattern. A range such as
+// [:] in pattern matches any index. An index such as [443] matches an array
+// index or the key of a map[int]T, but not a key of "443". Other map keys
+// are compared by name.
+func (l *Layers) SetStrategy(pattern string, strategy Strategy) error {
+	p, err := path.CompilePath(pattern)
+	if err != nil {
+		return err
+	}
+	if strategy.Arrays == MergeArraysByKey && strategy.MergeKey == "" {
+		return fmt.Errorf("merge key required for %s at %s", strategy.Arrays, pattern)
+	}
+	l.strategies = append(l.strategies, pathStrategy{pattern: p, strategy: strategy})
+	return nil
+}
+
+func (l *Layers) SetDefaultStrategy(strategy Strategy) {
+	l.defaultStrategy = strategy
+}
+
+func matchPattern(pattern, p path.Path) bool {
+	if len(pattern) != len(p) {
+		return false
+	}
+	for i, segment := range pattern {
+		if r, ok := segment.(*key.Range); ok {
+			index, ok := p[i].(key.Value[int])
+			if !ok || index.X < r.Start || (!r.Tail && index.X >= r.End) {
+				return false
+			}
+			continue
+		}
+		switch p[i].(type) {
+		case key.Value[string], key.Value[int]:
+			// an index such as [0] only matches an array index or int map key
+			if segment != p[i] {
+				return false
+			}
+		default:
+			// other map keys, such as those of a map[bool]T, match by name
+			if value.KeyName(segment) != value.KeyName(p[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (l *Layers) strategyFor(p path.Path) Strategy {
+	for i := len(l.strategies) - 1; i >= 0; i-- {
+		if matchPattern(l.strategies[i].pattern, p) {
+			return l.strategies[i].strategy
+		}
+	}
+	return l.defaultStrategy
+}
+
+func childPath(p path.Path, k key.Interface) path.Path {
+	child := make(path.Path, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, k)
+}
+
+// Merge combines all the layers into a new tree of native values. The source
+// of each merged node is a value.Provenance ending in the LayerSource of the
+// layer which set it.
+func (l *Layers) Merge() (value.Value, error) {
+	var result value.Value
+	for n := range l.layers {
+		layer := &l.layers[n]
+		if layer.Value == nil {
+			continue
+		}
+		var err error
+		result, err = l.merge(path.Path{}, result, layer.Value, layer)
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", layer.Name, err)
+		}
+	}
+	if result == nil {
+		return value.NewNull(value.UnknownSource), nil
+	}
+	return result, nil
+}
+
+// merge combines over from layer with base, which may be nil. It returns nil
+// if the value is deleted.
+func (l *Layers) merge(p path.Path, base, over value.Value, layer *Layer) (value.Value, error) {
+	if over.Kind() == value.NullKind {
+		return nil, nil
+	}
+	overSource := &LayerSource{Layer: layer.Name, Source: over.Source()}
+	var source value.Source = overSource
+	if base != nil {
+		source = value.ProvenanceOf(base.Source()).Then(value.OverriddenBy, overSource)
+	}
+	switch {
+	case over.Kind() == value.MapKind:
+		baseMap, _ := base.(value.Map)
+		if baseMap != nil {
+			source = value.ProvenanceOf(base.Source()).Then(value.MergedWith, overSource)
+		}
+		return l.mergeMap(p, baseMap, over, layer, source)
+	case over.Kind() == value.ArrayKind:
+		baseArray, _ := base.(value.Array)
+		strategy := l.strategyFor(p)
+		if baseArray == nil || strategy.Arrays == ReplaceArrays {
+			return l.mergeArray(p, nil, over, layer, Strategy{}, source)
+		}
+		source = value.ProvenanceOf(base.Source()).Then(value.MergedWith, overSource)
+		return l.mergeArray(p, baseArray, over, layer, strategy, source)
+	}
+	return value.WithSource(over, source)
+}
+
+func (l *Layers) mergeMap(p path.Path, base value.Map, over value.Value, layer *Layer, source value.Source) (value.Value, error) {
+	overMap, ok := over.(value.Map)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected map, but got %T", p.String(), over)
+	}
+	elements := make(map[string]value.Value)
+	keys := make(map[string]key.Interface)
+	if base != nil {
+		err := base.ForEach(func(k key.Interface, child value.Value) error {
+			name := value.KeyName(k)
+			elements[name] = child
+			keys[name] = k
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := overMap.ForEach(func(k key.Interface, child value.Value) error {
+		name := value.KeyName(k)
+		// keep a key such as the 443 of a map[int]T over its name
+		if _, ok := k.(key.Value[string]); !ok || keys[name] == nil {
+			keys[name] = k
+		}
+		merged, err := l.merge(childPath(p, keys[name]), elements[name], child, layer)
+		if err != nil {
+			return err
+		}
+		if merged == nil {
+			delete(elements, name)
+			delete(keys, name)
+		} else {
+			elements[name] = merged
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	mergedKeys := make([]key.Interface, 0, len(elements))
+	mergedValues := make([]value.Value, 0, len(elements))
+	for name, element := range elements {
+		mergedKeys = append(mergedKeys, keys[name])
+		mergedValues = append(mergedValues, element)
+	}
+	return value.NewKeyedMap(mergedKeys, mergedValues, source)
+}
+
+// copyElement copies an array element from layer. Unlike in maps a null
+// element is kept.
+func (l *Layers) copyElement(p path.Path, v value.Value, layer *Layer) (value.Value, error) {
+	if v.Kind() == value.NullKind {
+		return value.NewNull(&LayerSource{Layer: layer.Name, Source: v.Source()}), nil
+	}
+	return l.merge(p, nil, v, layer)
+}
+
+func (l *Layers) mergeArray(p path.Path, base value.Array, over value.Value, layer *Layer, strategy Strategy, source value.Source) (value.Value, error) {
+	overArray, ok := over.(value.Array)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected array, but got %T", p.String(), over)
+	}
+	var elements []value.Value
+	byKey := make(map[string]int)
+	if base != nil {
+		err := base.ForEach(func(k key.Interface, child value.Value) error {
+			if strategy.Arrays == MergeArraysByKey {
+				if id, ok := elementKey(child, strategy.MergeKey); ok {
+					byKey[id] = len(elements)
+				}
+			}
+			elements = append(elements, child)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := overArray.ForEach(func(k key.Interface, child value.Value) error {
+		if strategy.Arrays == MergeArraysByKey {
+			if id, ok := elementKey(child, strategy.MergeKey); ok {
+				if n, ok := byKey[id]; ok {
+					merged, err := l.merge(childPath(p, key.Value[int]{X: n}), elements[n], child, layer)
+					if err != nil {
+						return err
+					}
+					elements[n] = merged
+					return nil
+				}
+				byKey[id] = len(elements)
+			}
+		}
+		copied, err := l.copyElement(childPath(p, key.Value[int]{X: len(elements)}), child, layer)
+		if err != nil {
+			return err
+		}
+		elements = append(elements, copied)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.NewArray(elements, source), nil
+}
+
+// elementKey returns the value of the field identifying an array element.
+func elementKey(v value.Value, field string) (string, bool) {
+	m, ok := v.(value.Map)
+	if !ok {
+		return "", false
+	}
+	id, err := m.Field(key.Value[string]{X: field})
+	if err != nil {
+		return "", false
+	}
+	s, ok := id.(value.Simple)
+	if !ok {
+		return "", false
+	}
+	return s.String(), true
+}
+
+// Winner returns the name of the layer which set the merged value v.
+func Winner(v value.Value) (string, bool) {
+	source, ok := value.ProvenanceOf(v.Source()).Current().(*LayerSource)
+	if !ok {
+		return "", false
+	}
+	return source.Layer, true
+}
@@ -0,0 +1,228 @@
+package layers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/reflected"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+func mustFromGo(t *testing.T, obj any, source string) value.Value {
+	v, err := reflected.FromGo(obj, &sourceName{source})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return v
+}
+
+type sourceName struct {
+	name string
+}
+
+func (s *sourceName) String() string {
+	return s.name
+}
+
+func TestMerge(t *testing.T) {
+	defaults := mustFromGo(t, map[string]any{
+		"name":  "service",
+		"debug": "false",
+		"tags":  []any{"a", "b"},
+		"ports": []any{
+			map[string]any{"name": "http", "port": "80"},
+			map[string]any{"name": "admin", "port": "9000"},
+		},
+		"extra": map[string]any{"keep": "yes", "drop": "no"},
+	}, "defaults.yaml")
+	prod := mustFromGo(t, map[string]any{
+		"debug": "true",
+		"tags":  []any{"c"},
+		"ports": []any{
+			map[string]any{"name": "http", "port": "8080"},
+			map[string]any{"name": "metrics", "port": "9100"},
+		},
+		"extra": map[string]any{"drop": nil},
+	}, "prod.yaml")
+
+	l := New().Add("defaults", defaults).Add("prod", prod)
+	if err := l.SetStrategy(".tags", Strategy{Arrays: AppendArrays}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := l.SetStrategy(".ports", Strategy{Arrays: MergeArraysByKey, MergeKey: "name"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	merged, err := l.Merge()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]any{
+		"name":  "service",
+		"debug": "true",
+		"tags":  []any{"a", "b", "c"},
+		"ports": []any{
+			map[string]any{"name": "http", "port": "8080"},
+			map[string]any{"name": "admin", "port": "9000"},
+			map[string]any{"name": "metrics", "port": "9100"},
+		},
+		"extra": map[string]any{"keep": "yes"},
+	}
+	if !reflect.DeepEqual(merged.WithoutSource(), expected) {
+		t.Errorf("expected %v, but got %v", expected, merged.WithoutSource())
+	}
+
+	winners := map[string]string{
+		".name":          "defaults",
+		".debug":         "prod",
+		".ports[0].port": "prod",
+		".ports[1].port": "defaults",
+		".extra.keep":    "defaults",
+	}
+	for p, expected := range winners {
+		compiled, err := path.CompilePath(p)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		v, err := compiled.EvaluateFor(merged)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", p, err)
+		}
+		winner, ok := Winner(v)
+		if !ok || winner != expected {
+			t.Errorf("%s: expected winner %s, but got %s", p, expected, winner)
+		}
+	}
+}
+
+func TestStrategyKeys(t *testing.T) {
+	defaults := mustFromGo(t, map[string]any{
+		"ports": map[int]any{443: map[string]any{"hosts": []any{"a"}}},
+	}, "defaults.yaml")
+	prod := mustFromGo(t, map[string]any{
+		"ports": map[string]any{"443": map[string]any{"hosts": []any{"b"}}},
+	}, "prod.yaml")
+	l := New().Add("defaults", defaults).Add("prod", prod)
+	if err := l.SetStrategy(".ports[443].hosts", Strategy{Arrays: AppendArrays}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	merged, err := l.Merge()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]any{
+		"ports": map[string]any{"443": map[string]any{"hosts": []any{"a", "b"}}},
+	}
+	if !reflect.DeepEqual(merged.WithoutSource(), expected) {
+		t.Errorf("expected %v, but got %v", expected, merged.WithoutSource())
+	}
+
+	// [0] matches an array index but not a map key of "0"
+	for _, items := range []struct {
+		defaults, prod any
+		expected       any
+	}{
+		{
+			defaults: []any{map[string]any{"name": "web", "hosts": []any{"a"}}},
+			prod:     []any{map[string]any{"name": "web", "hosts": []any{"b"}}},
+			expected: []any{map[string]any{"name": "web", "hosts": []any{"a", "b"}}},
+		},
+		{
+			defaults: map[string]any{"0": map[string]any{"hosts": []any{"a"}}},
+			prod:     map[string]any{"0": map[string]any{"hosts": []any{"b"}}},
+			expected: map[string]any{"0": map[string]any{"hosts": []any{"b"}}},
+		},
+	} {
+		l := New().
+			Add("defaults", mustFromGo(t, map[string]any{"items": items.defaults}, "defaults.yaml")).
+			Add("prod", mustFromGo(t, map[string]any{"items": items.prod}, "prod.yaml"))
+		if err := l.SetStrategy(".items", Strategy{Arrays: MergeArraysByKey, MergeKey: "name"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := l.SetStrategy(".items[0].hosts", Strategy{Arrays: AppendArrays}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		merged, err := l.Merge()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		expected := map[string]any{"items": items.expected}
+		if !reflect.DeepEqual(merged.WithoutSource(), expected) {
+			t.Errorf("expected %v, but got %v", expected, merged.WithoutSource())
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	defaults := mustFromGo(t, map[string]any{
+		"debug": "false",
+		"ports": []any{
+			map[string]any{"name": "http", "port": "80"},
+			map[string]any{"name": "admin", "port": "9000"},
+		},
+		"extra": map[string]any{"keep": "yes"},
+	}, "defaults.yaml")
+	staging := mustFromGo(t, map[string]any{
+		"debug": "true",
+		"extra": nil,
+	}, "staging.yaml")
+	prod := mustFromGo(t, map[string]any{
+		"ports": []any{
+			map[string]any{"name": "admin", "port": "9090"},
+		},
+	}, "prod.yaml")
+
+	l := New().Add("defaults", defaults).Add("staging", staging).Add("prod", prod)
+	if err := l.SetStrategy(".ports", Strategy{Arrays: MergeArraysByKey, MergeKey: "name"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tests := []struct {
+		path     string
+		selected []bool
+		reasons  []string
+	}{
+		{
+			path:     ".debug",
+			selected: []bool{false, true, false},
+			reasons:  []string{"overridden by staging", "highest precedence layer setting the value", "not set"},
+		},
+		{
+			path:     ".ports[1].port",
+			selected: []bool{false, false, true},
+			reasons:  []string{"overridden by prod", "not set", "highest precedence layer setting the value"},
+		},
+		{
+			path:     ".extra.keep",
+			selected: []bool{false, false, false},
+			reasons:  []string{"deleted by staging at .extra", "deleted at .extra", "not set"},
+		},
+	}
+	for _, test := range tests {
+		p, err := path.CompilePath(test.path)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		e, err := l.Explain(p)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.path, err)
+		}
+		for i, c := range e.Candidates {
+			if c.Selected != test.selected[i] || c.Reason != test.reasons[i] {
+				t.Errorf("%s: layer %s: expected %v %q, but got %v %q", test.path, c.Layer, test.selected[i], test.reasons[i], c.Selected, c.Reason)
+			}
+		}
+	}
+}
+
+func TestLayerSourceExcerpt(t *testing.T) {
+	file := value.NewSourceFile("prod.yaml", []byte("replicas: three\n"))
+	source := &LayerSource{Layer: "prod", Source: file.Span(10, 15)}
+	sb := strings.Builder{}
+	if err := value.RenderExcerpt(&sb, source, "expected a number"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(sb.String(), "replicas: three") {
+		t.Errorf("expected excerpt of the layer's file, but got\n%s", sb.String())
+	}
+}
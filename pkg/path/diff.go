@@ -2,6 +2,7 @@ package path
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/davidjspooner/dsvalue/pkg/key"
 	"github.com/davidjspooner/dsvalue/pkg/value"
@@ -117,7 +118,7 @@ func (d *diff) forEachMap(f func(index key.Interface, value value.Value) error)
 
 		child.pair.right = v
 		child.pair.left, _ = leftMap.Field(k)
-		if child.pair.left == nil {
+		if child.pair.left != nil {
 			//we must have seen this key in the left map
 			return nil
 		}
@@ -175,6 +176,10 @@ func diffVisitFn(p Path, v value.Value, visitType VisitType) error {
 	}
 	switch visitType {
 	case AtCollectionStart:
+		if sameValue(d.pair.left, d.pair.right) {
+			// shared subtrees, such as yaml aliases, cannot differ
+			return ErrSkipContents
+		}
 		leftArray, ok := d.pair.left.(value.Array)
 		if ok {
 			rightArray, ok := d.pair.right.(value.Array)
@@ -201,9 +206,15 @@ func diffVisitFn(p Path, v value.Value, visitType VisitType) error {
 				}
 			}
 		} else {
-			leftString := d.pair.left.(value.Simple).String()
-			rightString := d.pair.right.(value.Simple).String()
-			if leftString != rightString {
+			leftSimple, ok := d.pair.left.(value.Simple)
+			if !ok {
+				return fmt.Errorf("expected simple value at %s, got %T", p.String(), d.pair.left)
+			}
+			rightSimple, ok := d.pair.right.(value.Simple)
+			if !ok {
+				return fmt.Errorf("expected simple value at %s, got %T", p.String(), d.pair.right)
+			}
+			if leftSimple.String() != rightSimple.String() {
 				err := d.differenceHandlerFunc(p, d.pair.left, d.pair.right)
 				if err != nil {
 					return err
@@ -214,6 +225,17 @@ func diffVisitFn(p Path, v value.Value, visitType VisitType) error {
 	return nil
 }
 
+// sameValue reports whether left and right are the same collection object.
+func sameValue(left, right value.Value) bool {
+	if left == nil || right == nil {
+		return false
+	}
+	if t := reflect.TypeOf(left); t != reflect.TypeOf(right) || !t.Comparable() {
+		return false
+	}
+	return left == right
+}
+
 func Diff(left, right value.Value, differenceHandlerFunc func(p Path, left, right value.Value) error) error {
 	d := &diff{
 		pair: pair{
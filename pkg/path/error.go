@@ -19,3 +19,7 @@ type ErrEvaluation struct {
 func (e *ErrEvaluation) Error() string {
 	return fmt.Errorf("error evaluating path '%s': %s", e.Path, e.Inner).Error()
 }
+
+func (e *ErrEvaluation) Unwrap() error {
+	return e.Inner
+}
@@ -39,6 +39,21 @@ var comparisonFunc = map[value.Kind]func(left, right value.Value) (int, error){
 		r := right.(value.String)
 		return l.CompareTo(r)
 	},
+	value.TimestampKind: func(left, right value.Value) (int, error) {
+		l := left.(value.Timestamp)
+		r := right.(value.Timestamp)
+		return l.CompareTo(r)
+	},
+	value.DurationKind: func(left, right value.Value) (int, error) {
+		l := left.(value.Duration)
+		r := right.(value.Duration)
+		return l.CompareTo(r)
+	},
+	value.BytesKind: func(left, right value.Value) (int, error) {
+		l := left.(value.Bytes)
+		r := right.(value.Bytes)
+		return l.CompareTo(r)
+	},
 	value.ArrayKind: func(left, right value.Value) (int, error) {
 		return 0, fmt.Errorf("not implemented - pairFunc[ArrayKind]")
 	},
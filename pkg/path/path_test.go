@@ -1,11 +1,15 @@
-package path
+package path_test
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
 	"github.com/davidjspooner/dsvalue/pkg/reflected"
 	"github.com/davidjspooner/dsvalue/pkg/value"
 	"gopkg.in/yaml.v3"
@@ -44,15 +48,15 @@ func TestParsePath(t *testing.T) {
 		},
 		{
 			input:         "0",
-			expectedError: &ErrInvalidPath{Path: "0", Inner: errors.New("expected '. or [', but got '0'")},
+			expectedError: &path.ErrInvalidPath{Path: "0", Inner: errors.New("expected '. or [', but got '0'")},
 		},
 		{
 			input:         ".foo[0",
-			expectedError: &ErrInvalidPath{Path: ".foo[0", Inner: errors.New("expected ': or ]', but got <EOF>")},
+			expectedError: &path.ErrInvalidPath{Path: ".foo[0", Inner: errors.New("expected ': or ]', but got <EOF>")},
 		},
 		{
 			input:         ".foo[0].",
-			expectedError: &ErrInvalidPath{Path: ".foo[0].", Inner: errors.New("expected 'identifier', but got <EOF>")},
+			expectedError: &path.ErrInvalidPath{Path: ".foo[0].", Inner: errors.New("expected 'identifier', but got <EOF>")},
 		},
 		{
 			input:          ".",
@@ -60,12 +64,12 @@ func TestParsePath(t *testing.T) {
 		},
 		{
 			input:         ".foo bar",
-			expectedError: &ErrInvalidPath{Path: ".foo bar", Inner: errors.New("expected '. or [', but got ' '")},
+			expectedError: &path.ErrInvalidPath{Path: ".foo bar", Inner: errors.New("expected '. or [', but got ' '")},
 		},
 	}
 
 	for _, test := range tests {
-		path, err := CompilePath(test.input)
+		p, err := path.CompilePath(test.input)
 		if test.expectedError == nil && err != nil {
 			t.Errorf("Parsing path: %q, expected no error, but got %q", test.input, err)
 		} else if test.expectedError != nil && err == nil {
@@ -73,9 +77,9 @@ func TestParsePath(t *testing.T) {
 		} else if test.expectedError != nil && err != nil && test.expectedError.Error() != err.Error() {
 			t.Errorf("Parsing path: %q, expected %q, but got %q", test.input, test.expectedError, err)
 		} else if err == nil {
-			output := path.String()
+			output := p.String()
 			if output != test.expectedString {
-				t.Errorf("Parsing path: %q, expected %q, but got %q", test.input, test.expectedString, path)
+				t.Errorf("Parsing path: %q, expected %q, but got %q", test.input, test.expectedString, p)
 			}
 		}
 	}
@@ -142,7 +146,7 @@ func TestPathEvaluateFor(t *testing.T) {
 		return
 	}
 
-	path, err := CompilePath(".status.loadBalancer.ingress[:].ip")
+	p, err := path.CompilePath(".status.loadBalancer.ingress[:].ip")
 	if err != nil {
 		t.Errorf("Error parsing path: %v", err)
 		return
@@ -154,7 +158,7 @@ func TestPathEvaluateFor(t *testing.T) {
 		t.Errorf("Error creating reflected object: %v", err)
 		return
 	}
-	result, err := path.EvaluateFor(object)
+	result, err := p.EvaluateFor(object)
 	if err != nil {
 		t.Errorf("%s", err)
 		return
@@ -163,3 +167,130 @@ func TestPathEvaluateFor(t *testing.T) {
 	t.Logf("Value: %s", s)
 	_ = result
 }
+
+func TestSortBy(t *testing.T) {
+	names := []string{"web", "api", "db", "api"}
+	elements := make([]value.Value, len(names))
+	for i, name := range names {
+		elements[i] = value.NewMap(map[string]value.Value{
+			"name":     value.NewString(name, value.UnknownSource),
+			"priority": value.NewInt(i, value.UnknownSource),
+		}, value.UnknownSource)
+	}
+	array := value.NewArray(elements, value.UnknownSource)
+
+	p, err := path.CompilePath(".name")
+	if err != nil {
+		t.Fatalf("Error parsing path: %v", err)
+	}
+	if err := path.SortBy(array, p); err != nil {
+		t.Fatalf("Error sorting: %v", err)
+	}
+	got := array.WithoutSource().([]interface{})
+	expected := []string{"api:1", "api:3", "db:2", "web:0"}
+	for i, e := range got {
+		m := e.(map[string]any)
+		s := m["name"].(string) + ":" + m["priority"].(string)
+		if s != expected[i] {
+			t.Errorf("element %d: expected %q, but got %q", i, expected[i], s)
+		}
+	}
+
+	p, _ = path.CompilePath(".priority")
+	view, err := path.SortedViewBy(array, p, value.Descending())
+	if err != nil {
+		t.Fatalf("Error sorting: %v", err)
+	}
+	first, err := view.Index(key.Value[int]{X: 0})
+	if err != nil {
+		t.Fatalf("Error indexing view: %v", err)
+	}
+	if name := first.WithoutSource().(map[string]any)["name"]; name != "api" {
+		t.Errorf("expected api first in descending priority, but got %v", name)
+	}
+
+	// the caller's comparator is given the values at the path
+	byLength := func(left, right value.Value) (int, error) {
+		l, ok := left.(value.String)
+		r, ok2 := right.(value.String)
+		if !ok || !ok2 {
+			return 0, fmt.Errorf("expected names, but got %T and %T", left, right)
+		}
+		return len(l.String()) - len(r.String()), nil
+	}
+	p, _ = path.CompilePath(".name")
+	view, err = path.SortedViewBy(array, p, value.WithComparator(byLength))
+	if err != nil {
+		t.Fatalf("Error sorting: %v", err)
+	}
+	first, err = view.Index(key.Value[int]{X: 0})
+	if err != nil {
+		t.Fatalf("Error indexing view: %v", err)
+	}
+	if name := first.WithoutSource().(map[string]any)["name"]; name != "db" {
+		t.Errorf("expected the shortest name first, but got %v", name)
+	}
+}
+
+func TestDiffNull(t *testing.T) {
+	decode := func(text string) value.Value {
+		var obj any
+		if err := yaml.Unmarshal([]byte(text), &obj); err != nil {
+			t.Fatalf("Error decoding yaml: %v", err)
+		}
+		v, err := reflected.NewReflectedObject(reflect.ValueOf(obj), value.UnknownSource)
+		if err != nil {
+			t.Fatalf("Error creating reflected object: %v", err)
+		}
+		return v
+	}
+	left := decode("a: null\nb: 1\nc: null\n")
+	right := decode("a: null\nb: 2\nc: x\n")
+
+	var differences []string
+	err := path.Diff(left, right, func(p path.Path, l, r value.Value) error {
+		differences = append(differences, p.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error diffing: %v", err)
+	}
+	sort.Strings(differences)
+	if strings.Join(differences, ",") != ".b,.c" {
+		t.Errorf("expected differences at .b,.c, but got %v", differences)
+	}
+
+	err = path.Diff(left, left, func(p path.Path, l, r value.Value) error {
+		t.Errorf("unexpected difference at %s in a shared tree", p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error diffing: %v", err)
+	}
+
+	result, err := value.Compare(value.NewNull(value.UnknownSource), value.NewNull(value.UnknownSource))
+	if err != nil || result != 0 {
+		t.Errorf("expected nulls to compare equal, but got %d (%v)", result, err)
+	}
+}
+
+func TestWalkMaxDepth(t *testing.T) {
+	var obj any
+	if err := yaml.Unmarshal([]byte(sampleYaml), &obj); err != nil {
+		t.Fatalf("Error decoding yaml: %v", err)
+	}
+	object, err := reflected.NewReflectedObject(reflect.ValueOf(obj), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	visit := func(p path.Path, v value.Value, vt path.VisitType) error {
+		return nil
+	}
+	if err := path.Walk(object, visit, path.WithMaxDepth(5)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = path.Walk(object, visit, path.WithMaxDepth(2))
+	if !errors.Is(err, path.ErrMaxDepthExceeded) {
+		t.Errorf("expected ErrMaxDepthExceeded, but got %v", err)
+	}
+}
@@ -164,3 +164,31 @@ func CompilePath(text string) (Path, error) {
 	}
 	return path, nil
 }
+
+// EvaluatePointer returns the value a JSON pointer (RFC 6901) such as
+// "/spec/ports/0" selects in obj.
+func EvaluatePointer(obj value.Value, pointer string) (value.Value, error) {
+	if pointer == "" {
+		return obj, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	for _, segment := range strings.Split(pointer[1:], "/") {
+		segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		var field key.Interface = key.Value[string]{X: segment}
+		if obj.Kind() == value.ArrayKind {
+			index, err := strconv.Atoi(segment)
+			if err != nil {
+				return nil, &ErrInvalidPath{Path: pointer, Inner: err}
+			}
+			field = key.Value[int]{X: index}
+		}
+		var err error
+		obj, err = EvaluateFieldFor(obj, field)
+		if err != nil {
+			return nil, &ErrInvalidPath{Path: pointer, Inner: err}
+		}
+	}
+	return obj, nil
+}
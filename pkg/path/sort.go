@@ -0,0 +1,46 @@
+package path
+
+import (
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// ByPath returns a comparator which compares the values found at p within
+// each element. Elements where p cannot be evaluated sort before all others.
+func ByPath(p Path, compare value.CompareFunc) value.CompareFunc {
+	if compare == nil {
+		compare = value.Compare
+	}
+	return func(left, right value.Value) (int, error) {
+		l, err := p.EvaluateFor(left)
+		if err != nil {
+			l = nil
+		}
+		r, err := p.EvaluateFor(right)
+		if err != nil {
+			r = nil
+		}
+		if l == nil || r == nil {
+			return value.Compare(l, r)
+		}
+		return compare(l, r)
+	}
+}
+
+// SortBy sorts a in place by the values found at p within each element.
+// A comparator set with value.WithComparator compares those values.
+func SortBy(a value.ModifiableArray, p Path, options ...value.SortOption) error {
+	return value.Sort(a, byPathOptions(p, options)...)
+}
+
+// SortedViewBy returns a view of a ordered by the values found at p within
+// each element, as for SortBy.
+func SortedViewBy(a value.Array, p Path, options ...value.SortOption) (value.Array, error) {
+	return value.NewSortedView(a, byPathOptions(p, options)...)
+}
+
+func byPathOptions(p Path, options []value.SortOption) []value.SortOption {
+	options = append([]value.SortOption(nil), options...)
+	return append(options, value.WrapComparator(func(compare value.CompareFunc) value.CompareFunc {
+		return ByPath(p, compare)
+	}))
+}
@@ -22,12 +22,35 @@ func (e WalkError) Error() string {
 }
 
 const (
-	ErrSkipContents   WalkError = "skip contents"
-	ErrSkipRestOfWalk WalkError = "skip rest of walk"
+	ErrSkipContents     WalkError = "skip contents"
+	ErrSkipRestOfWalk   WalkError = "skip rest of walk"
+	ErrMaxDepthExceeded WalkError = "maximum depth exceeded"
 )
 
-func walk(aValue value.Value, path Path, visitFn func(p Path, v value.Value, vt VisitType) error) (err error) {
+type WalkOption func(*walkConfig) error
+
+type walkConfig struct {
+	maxDepth int
+}
+
+// WithMaxDepth limits how deeply Walk descends into nested collections. A
+// walk reaching a value nested more than depth levels below the root fails
+// with ErrMaxDepthExceeded. A depth of 0 means no limit.
+func WithMaxDepth(depth int) WalkOption {
+	return func(c *walkConfig) error {
+		if depth < 0 {
+			return fmt.Errorf("invalid maximum depth: %d", depth)
+		}
+		c.maxDepth = depth
+		return nil
+	}
+}
+
+func walk(aValue value.Value, path Path, visitFn func(p Path, v value.Value, vt VisitType) error, config *walkConfig) (err error) {
 	pathLen := len(path)
+	if config.maxDepth > 0 && pathLen > config.maxDepth {
+		return &ErrEvaluation{Path: path.String(), Inner: ErrMaxDepthExceeded}
+	}
 	kind := aValue.Kind()
 	switch kind {
 	case value.MapKind, value.ArrayKind:
@@ -52,7 +75,7 @@ func walk(aValue value.Value, path Path, visitFn func(p Path, v value.Value, vt
 			path = append(path[:pathLen], nil)
 			err = m.ForEach(func(k key.Interface, child value.Value) error {
 				path[pathLen] = k
-				err = walk(child, path, visitFn)
+				err = walk(child, path, visitFn, config)
 				return err
 			})
 		} else {
@@ -61,7 +84,7 @@ func walk(aValue value.Value, path Path, visitFn func(p Path, v value.Value, vt
 			path = append(path[:pathLen], key.Value[int]{})
 			err = array.ForEach(func(index key.Interface, child value.Value) error {
 				path[pathLen] = index
-				err = walk(child, path, visitFn)
+				err = walk(child, path, visitFn, config)
 				return err
 			})
 		}
@@ -72,9 +95,15 @@ func walk(aValue value.Value, path Path, visitFn func(p Path, v value.Value, vt
 	return err
 }
 
-func Walk(value value.Value, f func(p Path, v value.Value, vt VisitType) error) error {
+func Walk(value value.Value, f func(p Path, v value.Value, vt VisitType) error, options ...WalkOption) error {
+	config := walkConfig{}
+	for _, option := range options {
+		if err := option(&config); err != nil {
+			return err
+		}
+	}
 	path := Path{}
-	err := walk(value, path, f)
+	err := walk(value, path, f, &config)
 	switch err {
 	case ErrSkipContents, ErrSkipRestOfWalk:
 		return nil
@@ -0,0 +1,85 @@
+package reflected
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// Adapter describes how a go type not otherwise understood by this package
+// is presented as a value.Value and set from one.
+type Adapter struct {
+	// View presents rValue as a value. It is used by NewReflectedObject and
+	// FromGo.
+	View func(rValue reflect.Value, source value.Source) (value.Value, error)
+	// Set stores v into the settable rValue. It is used by the Set methods
+	// of the parent collection and by Decode.
+	Set func(rValue reflect.Value, v value.Value) error
+}
+
+var adapters = struct {
+	sync.RWMutex
+	byType map[reflect.Type]*Adapter
+}{byType: make(map[reflect.Type]*Adapter)}
+
+// RegisterAdapter installs adapter for values of type t, replacing any
+// adapter previously registered for t. Either function may be nil.
+func RegisterAdapter(t reflect.Type, adapter Adapter) {
+	adapters.Lock()
+	defer adapters.Unlock()
+	adapters.byType[t] = &adapter
+}
+
+// UnregisterAdapter removes any adapter registered for t.
+func UnregisterAdapter(t reflect.Type) {
+	adapters.Lock()
+	defer adapters.Unlock()
+	delete(adapters.byType, t)
+}
+
+func lookupAdapter(t reflect.Type) (*Adapter, bool) {
+	adapters.RLock()
+	defer adapters.RUnlock()
+	adapter, ok := adapters.byType[t]
+	return adapter, ok
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// TextAdapter returns an adapter for t which presents values as strings using
+// encoding.TextMarshaler and sets them with encoding.TextUnmarshaler.
+func TextAdapter(t reflect.Type) (Adapter, error) {
+	if !t.Implements(textMarshalerType) && !reflect.PointerTo(t).Implements(textMarshalerType) {
+		return Adapter{}, fmt.Errorf("%s does not implement encoding.TextMarshaler", t)
+	}
+	if !reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return Adapter{}, fmt.Errorf("%s does not implement encoding.TextUnmarshaler", t)
+	}
+	return Adapter{
+		View: func(rValue reflect.Value, source value.Source) (value.Value, error) {
+			m, ok := marshaler(rValue, textMarshalerType)
+			if !ok {
+				return nil, fmt.Errorf("cannot marshal %s", rValue.Type())
+			}
+			text, err := m.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			return value.NewString(string(text), source), nil
+		},
+		Set: func(rValue reflect.Value, v value.Value) error {
+			s, ok := v.(value.String)
+			if !ok {
+				return fmt.Errorf("cannot assign %s to %s", v.Kind(), rValue.Type())
+			}
+			text, err := s.StringOrError()
+			if err != nil {
+				return err
+			}
+			return rValue.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+		},
+	}, nil
+}
@@ -0,0 +1,400 @@
+package reflected
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// assign converts v to the type of target and stores it there. target must
+// be settable. Registered adapters take precedence; otherwise nil pointers are
+// allocated, maps created and slices resized as required.
+func assign(target reflect.Value, v value.Value) error {
+	if !target.CanSet() {
+		return fmt.Errorf("cannot set %s - value is not addressable", target.Type())
+	}
+	t := target.Type()
+	if adapter, ok := lookupAdapter(t); ok && adapter.Set != nil {
+		return adapter.Set(target, v)
+	}
+	if r, ok := v.(goValue); ok {
+		rv := reflect.ValueOf(r.Interface())
+		if rv.IsValid() && rv.Type().AssignableTo(t) {
+			target.Set(rv)
+			return nil
+		}
+	}
+	if v == nil || v.Kind() == value.NullKind {
+		target.Set(reflect.Zero(t))
+		return nil
+	}
+
+	switch {
+	case t == timeType:
+		return assignTime(target, v)
+	case t == durationType:
+		return assignDuration(target, v)
+	case isBytesType(t):
+		return assignBytes(target, v)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(t.Elem())
+		if !target.IsNil() {
+			elem.Elem().Set(target.Elem())
+		}
+		if err := assign(elem.Elem(), v); err != nil {
+			return err
+		}
+		target.Set(elem)
+		return nil
+	case reflect.Interface:
+		native, err := nativeInterface(v)
+		if err != nil {
+			return err
+		}
+		nv := reflect.ValueOf(native)
+		if !nv.Type().AssignableTo(t) {
+			return fmt.Errorf("cannot assign %T to %s", native, t)
+		}
+		target.Set(nv)
+		return nil
+	case reflect.Bool:
+		b, ok := v.(value.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign %s to %s", v.Kind(), t)
+		}
+		bv, err := b.Bool()
+		if err != nil {
+			return err
+		}
+		target.SetBool(bv)
+		return nil
+	case reflect.String:
+		s, ok := v.(value.String)
+		if !ok {
+			return fmt.Errorf("cannot assign %s to %s", v.Kind(), t)
+		}
+		sv, err := s.StringOrError()
+		if err != nil {
+			return err
+		}
+		target.SetString(sv)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := v.(value.Number)
+		if !ok {
+			return fmt.Errorf("cannot assign %s to %s", v.Kind(), t)
+		}
+		i, err := n.Int(t.Bits())
+		if err != nil {
+			return err
+		}
+		target.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := v.(value.Number)
+		if !ok {
+			return fmt.Errorf("cannot assign %s to %s", v.Kind(), t)
+		}
+		u, err := n.Unsigned(t.Bits())
+		if err != nil {
+			return err
+		}
+		target.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		n, ok := v.(value.Number)
+		if !ok {
+			return fmt.Errorf("cannot assign %s to %s", v.Kind(), t)
+		}
+		f, err := n.Float(t.Bits())
+		if err != nil {
+			return err
+		}
+		target.SetFloat(f)
+		return nil
+	case reflect.Complex64, reflect.Complex128:
+		n, ok := v.(value.Number)
+		if !ok {
+			return fmt.Errorf("cannot assign %s to %s", v.Kind(), t)
+		}
+		c, err := n.Complex(t.Bits())
+		if err != nil {
+			return err
+		}
+		target.SetComplex(c)
+		return nil
+	case reflect.Slice:
+		return assignSlice(target, v)
+	case reflect.Array:
+		return assignArray(target, v)
+	case reflect.Map:
+		return assignMap(target, v)
+	case reflect.Struct:
+		return assignStruct(target, v)
+	}
+	return fmt.Errorf("cannot assign %s to %s", v.Kind(), t)
+}
+
+// stringText returns the text of a value of StringKind.
+func stringText(v value.Value) (string, error) {
+	s, ok := v.(value.String)
+	if !ok {
+		return "", fmt.Errorf("expected string, but got %T", v)
+	}
+	return s.StringOrError()
+}
+
+// assignTime accepts timestamps and RFC 3339 strings. Reflected values
+// implement every simple interface, so the kind decides which conversion
+// applies.
+func assignTime(target reflect.Value, v value.Value) error {
+	var tv time.Time
+	var err error
+	switch v.Kind() {
+	case value.TimestampKind:
+		ts, ok := v.(value.Timestamp)
+		if !ok {
+			return fmt.Errorf("expected timestamp, but got %T", v)
+		}
+		tv, err = ts.Time()
+	case value.StringKind:
+		var text string
+		if text, err = stringText(v); err == nil {
+			tv, err = time.Parse(time.RFC3339Nano, text)
+		}
+	default:
+		return fmt.Errorf("cannot assign %s to %s", v.Kind(), target.Type())
+	}
+	if err != nil {
+		return err
+	}
+	target.Set(reflect.ValueOf(tv))
+	return nil
+}
+
+// assignDuration accepts durations, strings such as "5m" and integer
+// nanoseconds.
+func assignDuration(target reflect.Value, v value.Value) error {
+	var d time.Duration
+	var err error
+	switch v.Kind() {
+	case value.DurationKind:
+		dv, ok := v.(value.Duration)
+		if !ok {
+			return fmt.Errorf("expected duration, but got %T", v)
+		}
+		d, err = dv.Duration()
+	case value.StringKind:
+		var text string
+		if text, err = stringText(v); err == nil {
+			d, err = time.ParseDuration(text)
+		}
+	case value.NumberKind:
+		n, ok := v.(value.Number)
+		if !ok {
+			return fmt.Errorf("expected number, but got %T", v)
+		}
+		var i int64
+		i, err = n.Int(64)
+		d = time.Duration(i)
+	default:
+		return fmt.Errorf("cannot assign %s to %s", v.Kind(), target.Type())
+	}
+	if err != nil {
+		return err
+	}
+	target.SetInt(int64(d))
+	return nil
+}
+
+// assignBytes accepts bytes and base64 encoded strings.
+func assignBytes(target reflect.Value, v value.Value) error {
+	var data []byte
+	var err error
+	switch v.Kind() {
+	case value.BytesKind:
+		b, ok := v.(value.Bytes)
+		if !ok {
+			return fmt.Errorf("expected bytes, but got %T", v)
+		}
+		data, err = b.Bytes()
+		data = append([]byte(nil), data...)
+	case value.StringKind:
+		var text string
+		if text, err = stringText(v); err == nil {
+			data, err = base64.StdEncoding.DecodeString(text)
+		}
+	default:
+		return fmt.Errorf("cannot assign %s to %s", v.Kind(), target.Type())
+	}
+	if err != nil {
+		return err
+	}
+	target.SetBytes(data)
+	return nil
+}
+
+func assignSlice(target reflect.Value, v value.Value) error {
+	a, ok := v.(value.Array)
+	if !ok {
+		return fmt.Errorf("cannot assign %s to %s", v.Kind(), target.Type())
+	}
+	length, err := a.Length()
+	if err != nil {
+		return err
+	}
+	slice := reflect.MakeSlice(target.Type(), length, length)
+	err = a.ForEach(func(index key.Interface, child value.Value) error {
+		i := index.(key.Value[int]).X
+		if err := assign(slice.Index(i), child); err != nil {
+			return childError(index, child, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	target.Set(slice)
+	return nil
+}
+
+func assignArray(target reflect.Value, v value.Value) error {
+	a, ok := v.(value.Array)
+	if !ok {
+		return fmt.Errorf("cannot assign %s to %s", v.Kind(), target.Type())
+	}
+	length, err := a.Length()
+	if err != nil {
+		return err
+	}
+	if length > target.Len() {
+		return fmt.Errorf("cannot assign %d elements to %s", length, target.Type())
+	}
+	array := reflect.New(target.Type()).Elem()
+	err = a.ForEach(func(index key.Interface, child value.Value) error {
+		i := index.(key.Value[int]).X
+		if err := assign(array.Index(i), child); err != nil {
+			return childError(index, child, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	target.Set(array)
+	return nil
+}
+
+func assignMap(target reflect.Value, v value.Value) error {
+	m, ok := v.(value.Map)
+	if !ok {
+		return fmt.Errorf("cannot assign %s to %s", v.Kind(), target.Type())
+	}
+	t := target.Type()
+	result := reflect.MakeMap(t)
+	err := m.ForEach(func(k key.Interface, child value.Value) error {
+		rKey, err := mapKeyValue(t.Key(), k)
+		if err != nil {
+			return childError(k, child, err)
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if err := assign(elem, child); err != nil {
+			return childError(k, child, err)
+		}
+		result.SetMapIndex(rKey, elem)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	target.Set(result)
+	return nil
+}
+
+func assignStruct(target reflect.Value, v value.Value) error {
+	m, ok := v.(value.Map)
+	if !ok {
+		return fmt.Errorf("cannot assign %s to %s", v.Kind(), target.Type())
+	}
+	result := reflect.New(target.Type()).Elem()
+	result.Set(target)
+	fields := getStructFields(target.Type())
+	err := m.ForEach(func(k key.Interface, child value.Value) error {
+		name, ok := k.(key.Value[string])
+		if !ok {
+			return fmt.Errorf("expected key.Value[string], but got %T", k)
+		}
+		n, ok := fields.byName[name.X]
+		if !ok {
+			return childError(k, child, fmt.Errorf("no such field in %s", target.Type()))
+		}
+		field, err := fields.list[n].settableValue(result)
+		if err != nil {
+			return childError(k, child, err)
+		}
+		if err := assign(field, child); err != nil {
+			return childError(k, child, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	target.Set(result)
+	return nil
+}
+
+// nativeInterface converts v into plain go values, as would be found after
+// decoding into an any.
+func nativeInterface(v value.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case goValue:
+		return v.Interface(), nil
+	case value.Number:
+		switch value.NumberTypeOf(v) {
+		case value.IntegerNumber:
+			if i, err := v.Int(64); err == nil {
+				return i, nil
+			}
+			return v.Unsigned(64)
+		case value.BigIntegerNumber:
+			return value.BigIntOf(v)
+		case value.ComplexNumber:
+			return v.Complex(128)
+		case value.DecimalNumber:
+			return v.String(), nil
+		default:
+			return v.Float(64)
+		}
+	case value.Map:
+		result := make(map[string]interface{})
+		err := v.ForEach(func(k key.Interface, child value.Value) error {
+			name, ok := k.(key.Value[string])
+			if !ok {
+				return fmt.Errorf("expected key.Value[string], but got %T", k)
+			}
+			native, err := nativeInterface(child)
+			result[name.X] = native
+			return err
+		})
+		return result, err
+	case value.Array:
+		var result []interface{}
+		err := v.ForEach(func(k key.Interface, child value.Value) error {
+			native, err := nativeInterface(child)
+			result = append(result, native)
+			return err
+		})
+		return result, err
+	}
+	return v.WithoutSource(), nil
+}
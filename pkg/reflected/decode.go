@@ -0,0 +1,73 @@
+package reflected
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// ErrDecode reports the node which could not be stored into a go value. Path
+// is relative to the value being decoded.
+type ErrDecode struct {
+	Path   path.Path
+	Source value.Source
+	Inner  error
+}
+
+func (e *ErrDecode) Error() string {
+	source := value.UnknownSource
+	if e.Source != nil {
+		source = e.Source
+	}
+	return fmt.Sprintf("error decoding '%s' at %s: %s", e.Path.String(), source, e.Inner)
+}
+
+func (e *ErrDecode) Unwrap() error {
+	return e.Inner
+}
+
+// childError attributes err to the child found at k.
+func childError(k key.Interface, child value.Value, err error) error {
+	var source value.Source
+	if child != nil {
+		source = child.Source()
+	}
+	return childSourceError(k, source, err)
+}
+
+// childSourceError attributes err to the child found at k, whose source is
+// source. An ErrDecode from further down gains k at the start of its path.
+func childSourceError(k key.Interface, source value.Source, err error) error {
+	var decodeErr *ErrDecode
+	if errors.As(err, &decodeErr) {
+		decodeErr.Path = append(path.Path{k}, decodeErr.Path...)
+		return decodeErr
+	}
+	return &ErrDecode{Path: path.Path{k}, Source: source, Inner: err}
+}
+
+// Decode stores v into the go value pointed to by target, following the same
+// struct tag rules as NewReflectedObject.
+func Decode(v value.Value, target any) error {
+	rValue := reflect.ValueOf(target)
+	if rValue.Kind() != reflect.Ptr || rValue.IsNil() {
+		return fmt.Errorf("expected non-nil pointer, but got %T", target)
+	}
+	err := assign(rValue.Elem(), v)
+	if err == nil {
+		return nil
+	}
+	var decodeErr *ErrDecode
+	if errors.As(err, &decodeErr) {
+		return decodeErr
+	}
+	var source value.Source
+	if v != nil {
+		source = v.Source()
+	}
+	return &ErrDecode{Source: source, Inner: err}
+}
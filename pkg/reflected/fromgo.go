@@ -0,0 +1,295 @@
+package reflected
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+type ConverterFunc func(rValue reflect.Value, source value.Source) (value.Value, error)
+
+type FromGoOption func(*fromGoConfig) error
+
+type fromGoConfig struct {
+	converters map[reflect.Type]ConverterFunc
+}
+
+// WithConverter uses f to convert any go value of type t.
+func WithConverter(t reflect.Type, f ConverterFunc) FromGoOption {
+	return func(c *fromGoConfig) error {
+		if f == nil {
+			return fmt.Errorf("converter for %s must not be nil", t)
+		}
+		if c.converters == nil {
+			c.converters = make(map[reflect.Type]ConverterFunc)
+		}
+		c.converters[t] = f
+		return nil
+	}
+}
+
+var (
+	valueType         = reflect.TypeOf((*value.Value)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonNumberType    = reflect.TypeOf(json.Number(""))
+)
+
+// FromGo builds a native value tree holding a copy of obj. Unlike
+// NewReflectedObject the result does not refer back to obj, so it may be
+// modified freely. Values which cannot be converted are reported with an
+// ErrDecode, as for Decode, and cycles with an ErrCycle.
+func FromGo(obj any, source value.Source, options ...FromGoOption) (value.Value, error) {
+	config := fromGoConfig{}
+	for _, option := range options {
+		if err := option(&config); err != nil {
+			return nil, err
+		}
+	}
+	t := &trail{base: source}
+	v, err := config.fromGo(reflect.ValueOf(obj), t)
+	if err != nil {
+		var decodeErr *ErrDecode
+		var cycleErr *ErrCycle
+		if errors.As(err, &decodeErr) || errors.As(err, &cycleErr) {
+			return nil, err
+		}
+		return nil, &ErrDecode{Source: t.source(), Inner: err}
+	}
+	return v, nil
+}
+
+// marshaler returns rValue, or its address, if either implements t.
+func marshaler(rValue reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	if rValue.Type().Implements(t) {
+		if rValue.Kind() == reflect.Ptr && rValue.IsNil() {
+			return rValue, false
+		}
+		return rValue, true
+	}
+	if rValue.CanAddr() && reflect.PointerTo(rValue.Type()).Implements(t) {
+		return rValue.Addr(), true
+	}
+	return rValue, false
+}
+
+func (c *fromGoConfig) fromGo(rValue reflect.Value, t *trail) (value.Value, error) {
+	source := t.source()
+	if !rValue.IsValid() {
+		return value.NewNull(source), nil
+	}
+	t.nameRoot(rValue)
+	rt := rValue.Type()
+	if f, ok := c.converters[rt]; ok {
+		return f(rValue, source)
+	}
+	if adapter, ok := lookupAdapter(rt); ok && adapter.View != nil {
+		return adapter.View(rValue, source)
+	}
+	if rt.Implements(valueType) {
+		if rValue.Kind() == reflect.Ptr && rValue.IsNil() {
+			return value.NewNull(source), nil
+		}
+		return detach(rValue.Interface().(value.Value))
+	}
+	switch {
+	case rt == timeType:
+		return value.NewTimestamp(rValue.Interface().(time.Time), source), nil
+	case rt == durationType:
+		return value.NewDuration(time.Duration(rValue.Int()), source), nil
+	case rt == jsonNumberType:
+		return value.NewNumber(rValue.String(), source), nil
+	}
+	if m, ok := marshaler(rValue, jsonMarshalerType); ok {
+		data, err := m.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return c.fromJSON(data, t)
+	}
+	if m, ok := marshaler(rValue, textMarshalerType); ok {
+		text, err := m.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return value.NewString(string(text), source), nil
+	}
+
+	if isBytesType(rt) {
+		if rValue.IsNil() {
+			return value.NewNull(source), nil
+		}
+		return value.NewBytes(bytes.Clone(rValue.Bytes()), source), nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rValue.IsNil() {
+			return value.NewNull(source), nil
+		}
+		if err := t.visit(rValue); err != nil {
+			return nil, err
+		}
+		return c.fromGo(rValue.Elem(), t)
+	case reflect.Bool:
+		return value.NewBool(rValue.Bool(), source), nil
+	case reflect.String:
+		return value.NewString(rValue.String(), source), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.NewInt(rValue.Int(), source), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.NewUnsigned(rValue.Uint(), source), nil
+	case reflect.Float32:
+		return value.NewFloat(float32(rValue.Float()), source), nil
+	case reflect.Float64:
+		return value.NewFloat(rValue.Float(), source), nil
+	case reflect.Complex64:
+		return value.NewComplex(complex64(rValue.Complex()), source), nil
+	case reflect.Complex128:
+		return value.NewComplex(rValue.Complex(), source), nil
+	case reflect.Slice, reflect.Array:
+		if rt.Kind() == reflect.Slice && rValue.IsNil() {
+			return value.NewNull(source), nil
+		}
+		if err := t.visit(rValue); err != nil {
+			return nil, err
+		}
+		elements := make([]value.Value, rValue.Len())
+		for i := range elements {
+			index := key.Value[int]{X: i}
+			childTrail := t.child(index, indexSegment(i))
+			child, err := c.fromGo(rValue.Index(i), childTrail)
+			if err != nil {
+				return nil, childPathError(index, childTrail.source(), err)
+			}
+			elements[i] = child
+		}
+		return value.NewArray(elements, source), nil
+	case reflect.Map:
+		if rValue.IsNil() {
+			return value.NewNull(source), nil
+		}
+		if err := t.visit(rValue); err != nil {
+			return nil, err
+		}
+		keys := make([]key.Interface, 0, rValue.Len())
+		elements := make([]value.Value, 0, rValue.Len())
+		iter := rValue.MapRange()
+		for iter.Next() {
+			k, err := mapKeyInterface(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			childTrail := t.child(k, mapKeySegment(iter.Key()))
+			child, err := c.fromGo(iter.Value(), childTrail)
+			if err != nil {
+				return nil, childPathError(k, childTrail.source(), err)
+			}
+			keys = append(keys, k)
+			elements = append(elements, child)
+		}
+		return value.NewKeyedMap(keys, elements, source)
+	case reflect.Struct:
+		fields := getStructFields(rt)
+		elements := make(map[string]value.Value, len(fields.list))
+		for n := range fields.list {
+			field := &fields.list[n]
+			reflectedChild := field.fieldValue(rValue)
+			if !field.present(reflectedChild) {
+				continue
+			}
+			fieldKey := key.Value[string]{X: field.name}
+			childTrail := t.child(fieldKey, "."+field.goName)
+			child, err := c.fromGo(reflectedChild, childTrail)
+			if err != nil {
+				return nil, childPathError(fieldKey, childTrail.source(), err)
+			}
+			elements[field.name] = child
+		}
+		return value.NewMap(elements, source), nil
+	}
+	return nil, fmt.Errorf("unsupported kind: %s", rt.Kind())
+}
+
+// detach copies v into native nodes. Collections may be modified in place
+// and reflected values share memory with go values, so both are copied;
+// other simple values are immutable and returned as they are.
+func detach(v value.Value) (value.Value, error) {
+	switch v.Kind() {
+	case value.MapKind:
+		m, ok := v.(value.Map)
+		if !ok {
+			return nil, fmt.Errorf("expected map, but got %T", v)
+		}
+		var keys []key.Interface
+		var elements []value.Value
+		err := m.ForEach(func(k key.Interface, child value.Value) error {
+			copy, err := detach(child)
+			if err != nil {
+				return childPathError(k, child.Source(), err)
+			}
+			keys = append(keys, k)
+			elements = append(elements, copy)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return value.NewKeyedMap(keys, elements, v.Source())
+	case value.ArrayKind:
+		a, ok := v.(value.Array)
+		if !ok {
+			return nil, fmt.Errorf("expected array, but got %T", v)
+		}
+		var elements []value.Value
+		err := a.ForEach(func(index key.Interface, child value.Value) error {
+			copy, err := detach(child)
+			if err != nil {
+				return childPathError(index, child.Source(), err)
+			}
+			elements = append(elements, copy)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return value.NewArray(elements, v.Source()), nil
+	}
+	if _, ok := v.(goValue); !ok {
+		return v, nil
+	}
+	if b, ok := v.(value.Bytes); ok && v.Kind() == value.BytesKind {
+		data, err := b.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		return value.NewBytes(bytes.Clone(data), v.Source()), nil
+	}
+	return value.WithSource(v, v.Source())
+}
+
+func (c *fromGoConfig) fromJSON(data []byte, t *trail) (value.Value, error) {
+	var obj any
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return c.fromGo(reflect.ValueOf(obj), t)
+}
+
+// childPathError attributes err to the child found at k, as an ErrDecode.
+// Cycle errors already carry their full path and are returned unchanged.
+func childPathError(k key.Interface, source value.Source, err error) error {
+	if _, ok := err.(*ErrCycle); ok {
+		return err
+	}
+	return childSourceError(k, source, err)
+}
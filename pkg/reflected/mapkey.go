@@ -0,0 +1,178 @@
+package reflected
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+)
+
+// mapKeyInterface converts the key of a go map into a key.Interface.
+// String kinds and encoding.TextMarshaler types become key.Value[string],
+// integers key.Value[int] and bools key.Value[bool]. Reflected views and
+// FromGo both use it, so a go map gives the same keys either way.
+func mapKeyInterface(k reflect.Value) (key.Interface, error) {
+	if k.Kind() == reflect.Interface {
+		if k.IsNil() {
+			return nil, fmt.Errorf("unsupported nil map key")
+		}
+		k = k.Elem()
+	}
+	if k.Kind() == reflect.String {
+		return key.Value[string]{X: k.String()}, nil
+	}
+	if m, ok := marshaler(k, textMarshalerType); ok {
+		text, err := m.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return key.Value[string]{X: string(text)}, nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return key.Value[int]{X: int(k.Int())}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if k.Uint() > math.MaxInt {
+			return key.Value[string]{X: strconv.FormatUint(k.Uint(), 10)}, nil
+		}
+		return key.Value[int]{X: int(k.Uint())}, nil
+	case reflect.Bool:
+		return key.Value[bool]{X: k.Bool()}, nil
+	}
+	return nil, fmt.Errorf("unsupported map key type %s", k.Type())
+}
+
+// mapKeyValue converts k to a value suitable for use as a key of a map with
+// key type t. It is the inverse of mapKeyInterface; in addition a
+// key.Value[string] is parsed when t is an integer or bool.
+func mapKeyValue(t reflect.Type, k key.Interface) (reflect.Value, error) {
+	var x reflect.Value
+	switch k := k.(type) {
+	case key.Value[string]:
+		x = reflect.ValueOf(k.X)
+	case key.Value[int]:
+		x = reflect.ValueOf(k.X)
+	case key.Value[bool]:
+		x = reflect.ValueOf(k.X)
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot use %T as key of type %s", k, t)
+	}
+	if x.Type().AssignableTo(t) {
+		result := reflect.New(t).Elem()
+		result.Set(x)
+		return result, nil
+	}
+	if x.Kind() == reflect.String {
+		if t.Kind() == reflect.String {
+			return x.Convert(t), nil
+		}
+		if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+			result := reflect.New(t)
+			err := result.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(x.String()))
+			return result.Elem(), err
+		}
+	}
+
+	result := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		var err error
+		switch x.Kind() {
+		case reflect.Int:
+			i = x.Int()
+		case reflect.String:
+			i, err = strconv.ParseInt(x.String(), 10, 64)
+		default:
+			err = fmt.Errorf("cannot use %s as key of type %s", k, t)
+		}
+		if err == nil && result.OverflowInt(i) {
+			err = fmt.Errorf("key %s overflows %s", k, t)
+		}
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.SetInt(i)
+		return result, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		var err error
+		switch x.Kind() {
+		case reflect.Int:
+			if x.Int() < 0 {
+				err = fmt.Errorf("key %s overflows %s", k, t)
+			}
+			u = uint64(x.Int())
+		case reflect.String:
+			u, err = strconv.ParseUint(x.String(), 10, 64)
+		default:
+			err = fmt.Errorf("cannot use %s as key of type %s", k, t)
+		}
+		if err == nil && result.OverflowUint(u) {
+			err = fmt.Errorf("key %s overflows %s", k, t)
+		}
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.SetUint(u)
+		return result, nil
+	case reflect.Bool:
+		switch x.Kind() {
+		case reflect.Bool:
+			result.SetBool(x.Bool())
+			return result, nil
+		case reflect.String:
+			b, err := strconv.ParseBool(x.String())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			result.SetBool(b)
+			return result, nil
+		}
+	case reflect.String:
+		if x.Kind() == reflect.Int {
+			return reflect.ValueOf(strconv.FormatInt(x.Int(), 10)).Convert(t), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s as key of type %s", k, t)
+}
+
+// mapKeys sorts the keys of a go map. Keys are ordered by type, so that bools
+// precede integers which precede strings, and then by value.
+type mapKeys struct {
+	keys   []key.Interface
+	values []reflect.Value
+}
+
+func (m *mapKeys) Len() int {
+	return len(m.keys)
+}
+
+func (m *mapKeys) Swap(i, j int) {
+	m.keys[i], m.keys[j] = m.keys[j], m.keys[i]
+	m.values[i], m.values[j] = m.values[j], m.values[i]
+}
+
+func (m *mapKeys) Less(i, j int) bool {
+	switch a := m.keys[i].(type) {
+	case key.Value[bool]:
+		b, ok := m.keys[j].(key.Value[bool])
+		return !ok || (!a.X && b.X)
+	case key.Value[int]:
+		switch b := m.keys[j].(type) {
+		case key.Value[bool]:
+			return false
+		case key.Value[int]:
+			return a.X < b.X
+		default:
+			return true
+		}
+	case key.Value[string]:
+		b, ok := m.keys[j].(key.Value[string])
+		return ok && a.X < b.X
+	}
+	return false
+}
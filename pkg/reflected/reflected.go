@@ -3,6 +3,7 @@ package reflected
 import (
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/davidjspooner/dsvalue/pkg/value"
 )
@@ -11,50 +12,109 @@ type Reflected interface {
 	value.Value
 }
 
-func NewReflectedObject(rValue reflect.Value, source value.Source) (value.Value, error) {
+// goValue is implemented by reflected views, giving the go value they present.
+type goValue interface {
+	Interface() interface{}
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+func isBytesType(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// isSimpleType reports whether t is a composite go type which is presented as
+// a single simple value.
+func isSimpleType(t reflect.Type) bool {
+	return t == timeType || isBytesType(t)
+}
+
+type Option func(*config) error
+
+type config struct {
+	unsorted bool
+}
+
+// Unsorted makes reflected maps iterate in go's map order rather than in key
+// order, which avoids the cost of sorting.
+func Unsorted() Option {
+	return func(c *config) error {
+		c.unsorted = true
+		return nil
+	}
+}
+
+func NewReflectedObject(rValue reflect.Value, source value.Source, options ...Option) (value.Value, error) {
+	c := &config{}
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, err
+		}
+	}
+	return newReflected(rValue, &trail{config: c, base: source})
+}
+
+func newReflected(rValue reflect.Value, t *trail) (value.Value, error) {
+	source := t.source()
+	t.nameRoot(rValue)
 	rk := rValue.Kind()
 	object, ok := rValue.Interface().(value.Value)
 	if ok {
 		return object, nil
 	}
+	if adapter, ok := lookupAdapter(rValue.Type()); ok && adapter.View != nil {
+		return adapter.View(rValue, source)
+	}
 	for rk == reflect.Ptr || rk == reflect.Interface {
 		if rValue.IsNil() {
 			return value.NewNull(source), nil
 		}
+		if err := t.visit(rValue); err != nil {
+			return nil, err
+		}
 		rValue = rValue.Elem()
 		rk = rValue.Kind()
 		object, ok := rValue.Interface().(value.Value)
 		if ok {
 			return object, nil
 		}
+		if adapter, ok := lookupAdapter(rValue.Type()); ok && adapter.View != nil {
+			return adapter.View(rValue, source)
+		}
+	}
+	if isSimpleType(rValue.Type()) {
+		return newReflectedSimple(rValue, source, t), nil
+	}
+	if err := t.visit(rValue); err != nil {
+		return nil, err
 	}
 	switch rk {
 	case reflect.Array, reflect.Slice:
 		return &reflectedArrayImpl{
 			rValue: rValue,
 			source: source,
+			trail:  t,
 		}, nil
 	case reflect.Map:
 		return &reflectedMapImpl{
 			rValue: rValue,
 			source: source,
+			trail:  t,
 		}, nil
 	case reflect.String:
-		return &reflectedSimpleImpl{
-			rValue: rValue,
-			source: source,
-		}, nil
+		return newReflectedSimple(rValue, source, t), nil
 	case reflect.Struct:
 		return &reflectedStructImpl{
 			rValue: rValue,
 			source: source,
+			trail:  t,
 		}, nil
 	default:
 		if rk < reflect.Array {
-			return &reflectedSimpleImpl{
-				rValue: rValue,
-				source: source,
-			}, nil
+			return newReflectedSimple(rValue, source, t), nil
 		}
 	}
 	return nil, fmt.Errorf("unsupported kind: %s", rk)
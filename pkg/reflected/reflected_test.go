@@ -0,0 +1,729 @@
+package reflected
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+type testMetadata struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type testPort struct {
+	Name string `yaml:"name"`
+	Port int    `yaml:"port"`
+}
+
+type testService struct {
+	testMetadata `json:",inline"`
+	Kind         string     `dsvalue:"kind" json:"ignored"`
+	Ports        []testPort `json:"ports"`
+	Secret       string     `json:"-"`
+	Comment      string     `json:"comment,omitempty"`
+	Replicas     *int
+	internal     int
+}
+
+func TestStructFields(t *testing.T) {
+	service := testService{
+		testMetadata: testMetadata{Name: "traefik"},
+		Kind:         "Service",
+		Ports:        []testPort{{Name: "web", Port: 80}, {Name: "websecure", Port: 443}},
+		Secret:       "hidden",
+	}
+	object, err := NewReflectedObject(reflect.ValueOf(service), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	m, ok := object.(value.Map)
+	if !ok {
+		t.Fatalf("expected map, but got %T", object)
+	}
+
+	var names []string
+	err = m.ForEach(func(k key.Interface, v value.Value) error {
+		names = append(names, k.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error iterating struct: %v", err)
+	}
+	if got := strings.Join(names, ""); got != ".name.kind.ports.Replicas" {
+		t.Errorf("unexpected fields %q", got)
+	}
+	if length, _ := m.Length(); length != 4 {
+		t.Errorf("expected length 4, but got %d", length)
+	}
+	if _, err := m.Field(key.Value[string]{X: "Secret"}); err == nil {
+		t.Errorf("expected Secret to be hidden")
+	}
+
+	ports, err := m.Field(key.Value[string]{X: "ports"})
+	if err != nil {
+		t.Fatalf("Error getting ports: %v", err)
+	}
+	second, err := ports.(value.Array).Index(key.Value[int]{X: 1})
+	if err != nil {
+		t.Fatalf("Error indexing ports: %v", err)
+	}
+	port, err := second.(value.Map).Field(key.Value[string]{X: "port"})
+	if err != nil {
+		t.Fatalf("Error getting port: %v", err)
+	}
+	if s := port.(value.Simple).String(); s != "443" {
+		t.Errorf("expected 443, but got %s", s)
+	}
+}
+
+type testAmbiguousA struct {
+	Shared string
+	Tagged string
+}
+
+type testAmbiguousB struct {
+	Shared string
+	Tagged string `json:"Tagged"`
+}
+
+type testDominance struct {
+	First string
+	testAmbiguousA
+	testAmbiguousB
+	Last string
+	*testMetadata
+}
+
+func TestStructFieldDominance(t *testing.T) {
+	obj := testDominance{First: "1", Last: "2", testMetadata: &testMetadata{Name: "n"}}
+	obj.testAmbiguousA.Tagged = "untagged"
+	obj.testAmbiguousB.Tagged = "tagged"
+	object, err := NewReflectedObject(reflect.ValueOf(obj), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	var names []string
+	err = object.(value.Map).ForEach(func(k key.Interface, v value.Value) error {
+		names = append(names, k.String()+"="+v.(value.Simple).String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error iterating struct: %v", err)
+	}
+	// Shared is ambiguous so is dropped, as encoding/json does
+	expected := ".First=1,.Tagged=tagged,.Last=2,.name=n"
+	if got := strings.Join(names, ","); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+	data, _ := json.Marshal(obj)
+	if string(data) != `{"First":"1","Tagged":"tagged","Last":"2","name":"n"}` {
+		t.Errorf("test struct disagrees with encoding/json: %s", data)
+	}
+}
+
+func TestSetReflected(t *testing.T) {
+	var service testService
+	object, err := NewReflectedObject(reflect.ValueOf(&service), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	m := object.(value.ModifiableMap)
+	if err := m.SetField(key.Value[string]{X: "name"}, value.NewString("traefik", value.UnknownSource)); err != nil {
+		t.Fatalf("Error setting name: %v", err)
+	}
+	if err := m.SetField(key.Value[string]{X: "Replicas"}, value.NewInt(3, value.UnknownSource)); err != nil {
+		t.Fatalf("Error setting replicas: %v", err)
+	}
+	port := value.NewMap(map[string]value.Value{
+		"name": value.NewString("web", value.UnknownSource),
+		"port": value.NewNumber("80", value.UnknownSource),
+	}, value.UnknownSource)
+	if err := m.SetField(key.Value[string]{X: "ports"}, value.NewArray([]value.Value{port}, value.UnknownSource)); err != nil {
+		t.Fatalf("Error setting ports: %v", err)
+	}
+
+	ports, _ := m.Field(key.Value[string]{X: "ports"})
+	if _, err := ports.(value.ModifiableArray).Append(port); err != nil {
+		t.Fatalf("Error appending port: %v", err)
+	}
+	labels, _ := m.Field(key.Value[string]{X: "labels"})
+	if labels != nil {
+		t.Fatalf("expected empty labels to be omitted")
+	}
+	if service.Name != "traefik" || service.Replicas == nil || *service.Replicas != 3 || len(service.Ports) != 2 || service.Ports[1].Port != 80 {
+		t.Errorf("unexpected result %+v", service)
+	}
+
+	labelMap, _ := NewReflectedObject(reflect.ValueOf(&service.Labels), value.UnknownSource)
+	if err := labelMap.(value.ModifiableMap).SetField(key.Value[string]{X: "app"}, value.NewString("web", value.UnknownSource)); err != nil {
+		t.Fatalf("Error setting label: %v", err)
+	}
+	if service.Labels["app"] != "web" {
+		t.Errorf("expected label to be set, got %v", service.Labels)
+	}
+}
+
+type testSource string
+
+func (s testSource) String() string {
+	return string(s)
+}
+
+func TestDecode(t *testing.T) {
+	port := func(name string, port value.Value) value.Value {
+		return value.NewMap(map[string]value.Value{
+			"name": value.NewString(name, testSource(name)),
+			"port": port,
+		}, testSource(name))
+	}
+	document := value.NewMap(map[string]value.Value{
+		"name": value.NewString("traefik", value.UnknownSource),
+		"ports": value.NewArray([]value.Value{
+			port("web", value.NewNumber("80", testSource("web.port"))),
+		}, value.UnknownSource),
+		"labels": value.NewMap(map[string]value.Value{
+			"app": value.NewString("traefik", value.UnknownSource),
+		}, value.UnknownSource),
+	}, value.UnknownSource)
+
+	var service testService
+	if err := Decode(document, &service); err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+	if service.Name != "traefik" || len(service.Ports) != 1 || service.Ports[0].Port != 80 || service.Labels["app"] != "traefik" {
+		t.Errorf("unexpected result %+v", service)
+	}
+
+	bad := value.NewMap(map[string]value.Value{
+		"ports": value.NewArray([]value.Value{
+			port("web", value.NewNumber("80", value.UnknownSource)),
+			port("websecure", value.NewString("443", testSource("file.yaml [Ln=7,Col=11]"))),
+		}, value.UnknownSource),
+	}, value.UnknownSource)
+	err := Decode(bad, &service)
+	decodeErr, ok := err.(*ErrDecode)
+	if !ok {
+		t.Fatalf("expected *ErrDecode, but got %v", err)
+	}
+	expected := "error decoding '.ports[1].port' at file.yaml [Ln=7,Col=11]: cannot assign String to int"
+	if decodeErr.Error() != expected {
+		t.Errorf("expected %q, but got %q", expected, decodeErr.Error())
+	}
+}
+
+type testTimes struct {
+	When  time.Time     `json:"when"`
+	Wait  time.Duration `json:"wait"`
+	Data  []byte        `json:"data"`
+	Delay time.Duration `json:"delay"`
+}
+
+func TestDecodeStrings(t *testing.T) {
+	when := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	expected := testTimes{When: when, Wait: 5 * time.Minute, Data: []byte("hello"), Delay: time.Second}
+	fields := map[string]any{
+		"when":  "2024-05-01T12:30:00Z",
+		"wait":  "5m",
+		"data":  "aGVsbG8=",
+		"delay": int64(time.Second),
+	}
+	reflectedDocument, err := NewReflectedObject(reflect.ValueOf(fields), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	nativeDocument := value.NewMap(map[string]value.Value{
+		"when":  value.NewString("2024-05-01T12:30:00Z", value.UnknownSource),
+		"wait":  value.NewString("5m", value.UnknownSource),
+		"data":  value.NewString("aGVsbG8=", value.UnknownSource),
+		"delay": value.NewInt(int64(time.Second), value.UnknownSource),
+	}, value.UnknownSource)
+	typedDocument := value.NewMap(map[string]value.Value{
+		"when":  value.NewTimestamp(when, value.UnknownSource),
+		"wait":  value.NewDuration(5*time.Minute, value.UnknownSource),
+		"data":  value.NewBytes([]byte("hello"), value.UnknownSource),
+		"delay": value.NewDuration(time.Second, value.UnknownSource),
+	}, value.UnknownSource)
+	for name, document := range map[string]value.Value{"reflected": reflectedDocument, "native": nativeDocument, "typed": typedDocument} {
+		var got testTimes
+		if err := Decode(document, &got); err != nil {
+			t.Errorf("%s: error decoding: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %+v, got %+v", name, expected, got)
+		}
+	}
+
+	bad := value.NewMap(map[string]value.Value{
+		"wait": value.NewBool(true, value.UnknownSource),
+	}, value.UnknownSource)
+	var got testTimes
+	if err := Decode(bad, &got); err == nil || !strings.Contains(err.Error(), "cannot assign Bool to time.Duration") {
+		t.Errorf("expected error assigning bool, got %v", err)
+	}
+}
+
+type testLevel int
+
+func (l testLevel) MarshalText() ([]byte, error) {
+	return []byte([]string{"debug", "info"}[l]), nil
+}
+
+func TestFromGo(t *testing.T) {
+	replicas := 2
+	service := testService{
+		testMetadata: testMetadata{Name: "traefik", Labels: map[string]string{"app": "web"}},
+		Ports:        []testPort{{Name: "web", Port: 80}},
+		Replicas:     &replicas,
+	}
+	object, err := FromGo(service, value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error converting: %v", err)
+	}
+	m, ok := object.(value.ModifiableMap)
+	if !ok {
+		t.Fatalf("expected native map, but got %T", object)
+	}
+	if err := m.SetField(key.Value[string]{X: "name"}, value.NewString("changed", value.UnknownSource)); err != nil {
+		t.Fatalf("Error setting name: %v", err)
+	}
+	if service.Name != "traefik" {
+		t.Errorf("expected original to be unchanged")
+	}
+	replicasValue, _ := m.Field(key.Value[string]{X: "Replicas"})
+	if n, ok := replicasValue.(value.Number); !ok || n.String() != "2" {
+		t.Errorf("expected replicas 2, but got %v", replicasValue)
+	}
+
+	levels, err := FromGo(map[string]any{"level": testLevel(1), "raw": json.RawMessage(`{"a":[1,2.5]}`)}, value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error converting: %v", err)
+	}
+	got := levels.WithoutSource().(map[string]any)
+	if got["level"] != "info" {
+		t.Errorf("expected level info, but got %v", got["level"])
+	}
+	if a := got["raw"].(map[string]any)["a"].([]any); a[1] != "2.5" {
+		t.Errorf("expected raw json to be decoded, but got %v", got["raw"])
+	}
+
+	port := testPort{Name: "web", Port: 80}
+	live, err := NewReflectedObject(reflect.ValueOf(&port), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	wrapped, err := FromGo(map[string]any{"port": live}, value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error converting: %v", err)
+	}
+	port.Port = 8080
+	detached, _ := wrapped.(value.Map).Field(key.Value[string]{X: "port"})
+	if _, ok := detached.(goValue); ok {
+		t.Fatalf("expected nested reflected view to be copied, but got %T", detached)
+	}
+	if got := detached.WithoutSource().(map[string]any)["port"]; fmt.Sprint(got) != "80" {
+		t.Errorf("expected copy to keep port 80, but got %v", got)
+	}
+
+	converted, err := FromGo([]testPort{{Name: "web"}}, value.UnknownSource,
+		WithConverter(reflect.TypeOf(testPort{}), func(rValue reflect.Value, source value.Source) (value.Value, error) {
+			return value.NewString(rValue.Field(0).String(), source), nil
+		}))
+	if err != nil {
+		t.Fatalf("Error converting: %v", err)
+	}
+	if s := converted.WithoutSource().([]any)[0]; s != "web" {
+		t.Errorf("expected converter to be used, but got %v", s)
+	}
+
+	_, err = FromGo(map[string]any{"ports": []any{80, make(chan int)}}, value.UnknownSource)
+	var decodeErr *ErrDecode
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected ErrDecode, but got %v", err)
+	}
+	if p := decodeErr.Path.String(); p != ".ports[1]" {
+		t.Errorf("expected error at .ports[1], but got %s", p)
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	ipType := reflect.TypeOf(net.IP{})
+	adapter, err := TextAdapter(ipType)
+	if err != nil {
+		t.Fatalf("Error creating adapter: %v", err)
+	}
+	RegisterAdapter(ipType, adapter)
+	defer UnregisterAdapter(ipType)
+
+	type host struct {
+		Address net.IP `json:"address"`
+	}
+	h := host{Address: net.ParseIP("192.168.201.128")}
+	object, err := NewReflectedObject(reflect.ValueOf(&h), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	m := object.(value.ModifiableMap)
+	address, err := m.Field(key.Value[string]{X: "address"})
+	if err != nil {
+		t.Fatalf("Error getting address: %v", err)
+	}
+	if s, ok := address.(value.String); !ok || s.String() != "192.168.201.128" {
+		t.Errorf("expected address string, but got %#v", address)
+	}
+	if err := m.SetField(key.Value[string]{X: "address"}, value.NewString("10.0.0.1", value.UnknownSource)); err != nil {
+		t.Fatalf("Error setting address: %v", err)
+	}
+	if !h.Address.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("expected address to be set, but got %s", h.Address)
+	}
+
+	var decoded host
+	err = Decode(value.NewMap(map[string]value.Value{
+		"address": value.NewString("not-an-ip", value.UnknownSource),
+	}, value.UnknownSource), &decoded)
+	if err == nil {
+		t.Errorf("expected error decoding invalid address")
+	}
+}
+
+type testColour string
+
+func TestMapKeys(t *testing.T) {
+	byPort := map[uint16]string{80: "web", 443: "websecure"}
+	object, err := NewReflectedObject(reflect.ValueOf(&byPort), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	m := object.(value.ModifiableMap)
+	name, err := m.Field(key.Value[int]{X: 443})
+	if err != nil || name.(value.Simple).String() != "websecure" {
+		t.Errorf("expected websecure, but got %v (%v)", name, err)
+	}
+	if _, err := m.Field(key.Value[int]{X: -1}); err == nil {
+		t.Errorf("expected error for negative key")
+	}
+	if err := m.SetField(key.Value[string]{X: "8080"}, value.NewString("alt", value.UnknownSource)); err != nil {
+		t.Fatalf("Error setting field: %v", err)
+	}
+	if byPort[8080] != "alt" {
+		t.Errorf("expected key 8080 to be set, got %v", byPort)
+	}
+	err = m.ForEach(func(k key.Interface, v value.Value) error {
+		if _, ok := k.(key.Value[int]); !ok {
+			t.Errorf("expected key.Value[int], but got %T", k)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error iterating: %v", err)
+	}
+
+	detached, err := FromGo(byPort, value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error converting: %v", err)
+	}
+	err = detached.(value.Map).ForEach(func(k key.Interface, v value.Value) error {
+		if _, ok := k.(key.Value[int]); !ok {
+			t.Errorf("expected FromGo to give key.Value[int], but got %T", k)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error iterating: %v", err)
+	}
+	if name, err := detached.(value.Map).Field(key.Value[int]{X: 443}); err != nil || name.(value.Simple).String() != "websecure" {
+		t.Errorf("expected websecure, but got %v (%v)", name, err)
+	}
+	object, _ = NewReflectedObject(reflect.ValueOf(byPort), value.UnknownSource)
+	err = path.Diff(object, detached, func(p path.Path, left, right value.Value) error {
+		t.Errorf("unexpected difference at %s", p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error comparing: %v", err)
+	}
+
+	colours := map[testColour]bool{"red": true}
+	object, _ = NewReflectedObject(reflect.ValueOf(colours), value.UnknownSource)
+	if _, err := object.(value.Map).Field(key.Value[string]{X: "red"}); err != nil {
+		t.Errorf("expected red to be found: %v", err)
+	}
+
+	flags := map[bool]int{true: 1}
+	object, _ = NewReflectedObject(reflect.ValueOf(flags), value.UnknownSource)
+	err = object.(value.Map).ForEach(func(k key.Interface, v value.Value) error {
+		if k.String() != "[true]" {
+			t.Errorf("expected [true], but got %s", k)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error iterating: %v", err)
+	}
+}
+
+type testNode struct {
+	Name     string      `json:"name"`
+	Parent   *testNode   `json:"parent,omitempty"`
+	Children []*testNode `json:"children,omitempty"`
+}
+
+func TestCycles(t *testing.T) {
+	root := &testNode{Name: "root"}
+	child := &testNode{Name: "child", Parent: root}
+	root.Children = []*testNode{child}
+
+	object, err := NewReflectedObject(reflect.ValueOf(root), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	children, err := object.(value.Map).Field(key.Value[string]{X: "children"})
+	if err != nil {
+		t.Fatalf("Error getting children: %v", err)
+	}
+	first, err := children.(value.Array).Index(key.Value[int]{X: 0})
+	if err != nil {
+		t.Fatalf("Error getting child: %v", err)
+	}
+	_, err = first.(value.Map).Field(key.Value[string]{X: "parent"})
+	cycleErr, ok := err.(*ErrCycle)
+	if !ok {
+		t.Fatalf("expected *ErrCycle, but got %v", err)
+	}
+	if cycleErr.Error() != "cycle detected at '.children[0].parent' (*reflected.testNode)" {
+		t.Errorf("unexpected error %q", cycleErr)
+	}
+
+	if _, err := FromGo(root, value.UnknownSource); err == nil {
+		t.Errorf("expected FromGo to detect cycle")
+	}
+
+	self := map[string]any{}
+	self["self"] = self
+	object, _ = NewReflectedObject(reflect.ValueOf(self), value.UnknownSource)
+	if _, err := object.(value.Map).Field(key.Value[string]{X: "self"}); err == nil {
+		t.Errorf("expected map cycle to be detected")
+	}
+
+	shared := &testNode{Name: "shared"}
+	siblings := []*testNode{shared, shared}
+	if _, err := FromGo(siblings, value.UnknownSource); err != nil {
+		t.Errorf("shared pointers are not cycles: %v", err)
+	}
+}
+
+func TestReflectedSimple(t *testing.T) {
+	type limits struct {
+		Small uint8   `json:"small"`
+		Ratio float32 `json:"ratio"`
+		Name  string  `json:"name"`
+		On    bool    `json:"on"`
+	}
+	object, err := NewReflectedObject(reflect.ValueOf(limits{Small: 200, Ratio: 0.1, Name: "x", On: true}), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	m := object.(value.Map)
+	field := func(name string) value.Simple {
+		v, err := m.Field(key.Value[string]{X: name})
+		if err != nil {
+			t.Fatalf("Error getting %s: %v", name, err)
+		}
+		return v.(value.Simple)
+	}
+
+	small := field("small").(value.Number)
+	if _, err := small.Int(8); err == nil {
+		t.Errorf("expected 200 to overflow int8")
+	}
+	if r, err := small.CompareTo(value.NewNumber("200", value.UnknownSource)); err != nil || r != 0 {
+		t.Errorf("expected 200 to equal native 200, but got %d (%v)", r, err)
+	}
+	if r, err := value.NewNumber("199.5", value.UnknownSource).CompareTo(small); err != nil || r != -1 {
+		t.Errorf("expected native 199.5 < 200, but got %d (%v)", r, err)
+	}
+	if s := field("ratio").String(); s != "0.1" {
+		t.Errorf("expected float32 to format as 0.1, but got %s", s)
+	}
+	if r, err := field("name").CompareTo(value.NewString("y", value.UnknownSource)); err != nil || r != -1 {
+		t.Errorf("expected x < y, but got %d (%v)", r, err)
+	}
+	if _, err := field("name").CompareTo(small); err == nil {
+		t.Errorf("expected error comparing string to number")
+	}
+	if b, err := field("on").(value.Bool).Bool(); err != nil || !b {
+		t.Errorf("expected true, but got %v (%v)", b, err)
+	}
+
+	// typed interfaces match the kind, so mixed kinds cannot be compared
+	if _, err := value.NewString("200", value.UnknownSource).CompareTo(small); err == nil {
+		t.Errorf("expected error comparing native string to reflected number")
+	}
+	if _, err := value.NewNumber("1", value.UnknownSource).CompareTo(field("on")); err == nil {
+		t.Errorf("expected error comparing native number to reflected bool")
+	}
+	if _, ok := small.(value.String); ok {
+		t.Errorf("expected reflected number not to implement value.String")
+	}
+	if _, ok := field("name").(value.Number); ok {
+		t.Errorf("expected reflected string not to implement value.Number")
+	}
+}
+
+func TestMapOrder(t *testing.T) {
+	m := map[string]int{"delta": 4, "alpha": 1, "charlie": 3, "bravo": 2}
+	object, err := NewReflectedObject(reflect.ValueOf(m), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	if length, err := object.(value.Map).Length(); err != nil || length != 4 {
+		t.Errorf("expected length 4, but got %d (%v)", length, err)
+	}
+	for i := 0; i < 5; i++ {
+		var names []string
+		err := object.(value.Map).ForEach(func(k key.Interface, v value.Value) error {
+			names = append(names, k.String())
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Error iterating: %v", err)
+		}
+		if got := strings.Join(names, ""); got != ".alpha.bravo.charlie.delta" {
+			t.Fatalf("expected sorted keys, but got %q", got)
+		}
+	}
+
+	mixed := map[any]int{"b": 1, 10: 2, 2: 3, true: 4}
+	object, err = NewReflectedObject(reflect.ValueOf(mixed), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	var names []string
+	object.(value.Map).ForEach(func(k key.Interface, v value.Value) error {
+		names = append(names, k.String())
+		return nil
+	})
+	if got := strings.Join(names, ""); got != "[true][2][10].b" {
+		t.Errorf("expected keys ordered by type, but got %q", got)
+	}
+
+	object, err = NewReflectedObject(reflect.ValueOf(m), value.UnknownSource, Unsorted())
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	count := 0
+	object.(value.Map).ForEach(func(k key.Interface, v value.Value) error {
+		count++
+		return nil
+	})
+	if count != 4 {
+		t.Errorf("expected 4 keys, but got %d", count)
+	}
+}
+
+func TestGoSource(t *testing.T) {
+	service := &testService{
+		testMetadata: testMetadata{Name: "traefik", Labels: map[string]string{"app": "web"}},
+		Ports:        []testPort{{Name: "web", Port: 80}, {Name: "websecure", Port: 443}},
+	}
+	object, err := NewReflectedObject(reflect.ValueOf(service), value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error creating reflected object: %v", err)
+	}
+	ports, _ := object.(value.Map).Field(key.Value[string]{X: "ports"})
+	port, _ := ports.(value.Array).Index(key.Value[int]{X: -1})
+	name, _ := port.(value.Map).Field(key.Value[string]{X: "name"})
+	if s := name.Source().String(); s != "testService.Ports[1].Name" {
+		t.Errorf("unexpected source %q", s)
+	}
+	labels, _ := object.(value.Map).Field(key.Value[string]{X: "labels"})
+	app, _ := labels.(value.Map).Field(key.Value[string]{X: "app"})
+	if s := app.Source().String(); s != `testService.Labels["app"]` {
+		t.Errorf("unexpected source %q", s)
+	}
+
+	object, _ = NewReflectedObject(reflect.ValueOf(service), testSource("service.go"))
+	if s := object.Source().String(); s != "service.go (testService)" {
+		t.Errorf("unexpected source %q", s)
+	}
+
+	detached, err := FromGo(service, value.UnknownSource)
+	if err != nil {
+		t.Fatalf("Error converting: %v", err)
+	}
+	kind, _ := detached.(value.Map).Field(key.Value[string]{X: "kind"})
+	if s := kind.Source().String(); s != "testService.Kind" {
+		t.Errorf("unexpected source %q", s)
+	}
+}
+
+type testRuleListener struct {
+	Protocol string `json:"protocol" validate:"oneof=tcp udp"`
+	Port     int    `json:"port" validate:"min=1,max=65535"`
+}
+
+type testRuleConfig struct {
+	Name      string             `json:"name" validate:"required,regexp=^[a-z][a-z0-9-]*$"`
+	Timeout   time.Duration      `json:"timeout" validate:"max=1m"`
+	Listeners []testRuleListener `json:"listeners" validate:"min=1"`
+	Owner     string             `json:"owner,omitempty" validate:"required"`
+}
+
+func TestValidate(t *testing.T) {
+	origin, err := FromGo(map[string]any{
+		"name":    "Web",
+		"timeout": "90s",
+		"listeners": []any{
+			map[string]any{"protocol": "tcp", "port": 80},
+			map[string]any{"protocol": "sctp", "port": 0},
+		},
+	}, value.UnknownSource)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	config := testRuleConfig{}
+	if err := Decode(origin, &config); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = Validate(&config, origin)
+	var validationErr *ErrValidation
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ErrValidation, but got %v", err)
+	}
+	expected := []struct {
+		expression, rule, source string
+	}{
+		{"testRuleConfig.Name", "regexp=^[a-z][a-z0-9-]*$", `value["name"]`},
+		{"testRuleConfig.Timeout", "max=1m", `value["timeout"]`},
+		{"testRuleConfig.Listeners[1].Protocol", "oneof=tcp udp", `value["listeners"][1]["protocol"]`},
+		{"testRuleConfig.Listeners[1].Port", "min=1", `value["listeners"][1]["port"]`},
+		{"testRuleConfig.Owner", "required", `value`},
+	}
+	if len(validationErr.Violations) != len(expected) {
+		t.Fatalf("expected %d violations, but got %s", len(expected), err)
+	}
+	for i, e := range expected {
+		v := validationErr.Violations[i]
+		if v.Expression != e.expression || v.Rule != e.rule || v.Source.String() != e.source {
+			t.Errorf("expected %s %s at %s, but got %s", e.expression, e.rule, e.source, v.String())
+		}
+	}
+
+	type badTag struct {
+		Name string `validate:"between=1"`
+	}
+	if err := Validate(&badTag{}, nil); err == nil || errors.As(err, &validationErr) {
+		t.Errorf("expected an invalid tag error, but got %v", err)
+	}
+}
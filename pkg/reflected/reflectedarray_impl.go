@@ -11,6 +11,7 @@ import (
 type reflectedArrayImpl struct {
 	rValue reflect.Value
 	source value.Source
+	trail  *trail
 }
 
 var _ value.ModifiableArray = &reflectedArrayImpl{}
@@ -37,7 +38,7 @@ func (o *reflectedArrayImpl) Index(index key.Interface) (value.Value, error) {
 		return nil, err
 	}
 	child := o.rValue.Index(safeIndex)
-	return NewReflectedObject(child, o.source)
+	return newReflected(child, o.trail.child(key.Value[int]{X: safeIndex}, indexSegment(safeIndex)))
 }
 func (o *reflectedArrayImpl) Length() (int, error) {
 	rk := o.rValue.Kind()
@@ -54,21 +55,40 @@ func (o *reflectedArrayImpl) Interface() interface{} {
 }
 
 func (o *reflectedArrayImpl) SetValue(value value.Value) error {
-	return fmt.Errorf("not implemented - SetValue")
+	return assign(o.rValue, value)
 }
 
-func (o *reflectedArrayImpl) SetIndex(index key.Interface, value value.Value) error {
-	return fmt.Errorf("not implemented - SetIndex")
+func (o *reflectedArrayImpl) SetIndex(index key.Interface, newValue value.Value) error {
+	nIndex, ok := index.(key.Value[int])
+	if !ok {
+		return fmt.Errorf("expected key.Value[int], but got %T", index)
+	}
+	safeIndex, err := value.NormalizeIndex(nIndex.X, o.rValue.Len())
+	if err != nil {
+		return err
+	}
+	return assign(o.rValue.Index(safeIndex), newValue)
 }
 
 func (o *reflectedArrayImpl) Append(value value.Value) (key.Interface, error) {
-	return key.Value[int]{}, fmt.Errorf("not implemented - Append")
+	if o.rValue.Kind() != reflect.Slice {
+		return key.Value[int]{}, fmt.Errorf("cannot append to %s", o.rValue.Type())
+	}
+	if !o.rValue.CanSet() {
+		return key.Value[int]{}, fmt.Errorf("cannot append to %s - value is not addressable", o.rValue.Type())
+	}
+	elem := reflect.New(o.rValue.Type().Elem()).Elem()
+	if err := assign(elem, value); err != nil {
+		return key.Value[int]{}, err
+	}
+	o.rValue.Set(reflect.Append(o.rValue, elem))
+	return key.Value[int]{X: o.rValue.Len() - 1}, nil
 }
 
 func (o *reflectedArrayImpl) ForEach(f func(index key.Interface, value value.Value) error) error {
 	i := key.Value[int]{}
 	for i.X = 0; i.X < o.rValue.Len(); i.X++ {
-		child, err := NewReflectedObject(o.rValue.Index(i.X), o.source)
+		child, err := newReflected(o.rValue.Index(i.X), o.trail.child(i, indexSegment(i.X)))
 		if err != nil {
 			return err
 		}
@@ -3,6 +3,7 @@ package reflected
 import (
 	"fmt"
 	"reflect"
+	"sort"
 
 	"github.com/davidjspooner/dsvalue/pkg/key"
 	"github.com/davidjspooner/dsvalue/pkg/value"
@@ -11,6 +12,7 @@ import (
 type reflectedMapImpl struct {
 	rValue reflect.Value
 	source value.Source
+	trail  *trail
 }
 
 var _ value.ModifiableMap = &reflectedMapImpl{}
@@ -25,21 +27,20 @@ func (o *reflectedMapImpl) Kind() value.Kind {
 
 func (o *reflectedMapImpl) Field(k key.Interface) (value.Value, error) {
 
-	safeKey, ok := k.(key.Value[string])
-	if !ok {
-		return nil, fmt.Errorf("expected key.Value[string], but got %T", k)
+	rKey, err := mapKeyValue(o.rValue.Type().Key(), k)
+	if err != nil {
+		return nil, err
 	}
 
-	child := o.rValue.MapIndex(reflect.ValueOf(safeKey.X))
+	child := o.rValue.MapIndex(rKey)
 	if !child.IsValid() {
 		return nil, fmt.Errorf("Field %q not found", k)
 	}
-	return NewReflectedObject(child, o.source)
+	return newReflected(child, o.trail.child(k, mapKeySegment(rKey)))
 }
 
 func (o *reflectedMapImpl) Length() (int, error) {
-	rk := o.rValue.Kind()
-	return 0, fmt.Errorf("Length not supported for %s (%s)", o.Kind(), rk)
+	return o.rValue.Len(), nil
 }
 
 func (o *reflectedMapImpl) Interface() interface{} {
@@ -47,27 +48,49 @@ func (o *reflectedMapImpl) Interface() interface{} {
 }
 
 func (o *reflectedMapImpl) SetValue(value value.Value) error {
-	return fmt.Errorf("not implemented - SetValue")
+	return assign(o.rValue, value)
 }
 
 func (o *reflectedMapImpl) SetField(k key.Interface, value value.Value) error {
-	return fmt.Errorf("not implemented - SetField")
+	t := o.rValue.Type()
+	rKey, err := mapKeyValue(t.Key(), k)
+	if err != nil {
+		return err
+	}
+	if o.rValue.IsNil() {
+		if !o.rValue.CanSet() {
+			return fmt.Errorf("cannot create %s - value is not addressable", t)
+		}
+		o.rValue.Set(reflect.MakeMap(t))
+	}
+	elem := reflect.New(t.Elem()).Elem()
+	if err := assign(elem, value); err != nil {
+		return err
+	}
+	o.rValue.SetMapIndex(rKey, elem)
+	return nil
 }
 
 func (o *reflectedMapImpl) ForEach(f func(index key.Interface, value value.Value) error) error {
 	oKeys := o.rValue.MapKeys()
-	i := o.rValue.Interface()
-	_ = i
-	for _, k := range oKeys {
+	keys := make([]key.Interface, len(oKeys))
+	for n, k := range oKeys {
+		ks, err := mapKeyInterface(k)
+		if err != nil {
+			return err
+		}
+		keys[n] = ks
+	}
+	if o.trail == nil || o.trail.config == nil || !o.trail.config.unsorted {
+		sort.Sort(&mapKeys{keys: keys, values: oKeys})
+	}
+	for n, k := range oKeys {
 		reflectedChild := o.rValue.MapIndex(k)
-		child, err := NewReflectedObject(reflectedChild, o.source)
-		i2 := reflectedChild.Interface()
-		_ = i2
+		child, err := newReflected(reflectedChild, o.trail.child(keys[n], mapKeySegment(k)))
 		if err != nil {
 			return err
 		}
-		ks := k.String()
-		if err = f(key.Value[string]{X: ks}, child); err != nil {
+		if err = f(keys[n], child); err != nil {
 			return err
 		}
 	}
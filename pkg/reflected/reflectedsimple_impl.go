@@ -2,7 +2,9 @@ package reflected
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
+	"time"
 
 	"github.com/davidjspooner/dsvalue/pkg/value"
 )
@@ -10,16 +12,65 @@ import (
 type reflectedSimpleImpl struct {
 	rValue reflect.Value
 	source value.Source
+	trail  *trail
 }
 
 var _ value.Simple = &reflectedSimpleImpl{}
 var _ value.ModifiableValue = &reflectedSimpleImpl{}
 var _ Reflected = &reflectedSimpleImpl{}
 
+// The typed views embed reflectedSimpleImpl, so that a reflected value only
+// implements the typed interface matching its Kind.
+type reflectedStringImpl struct{ *reflectedSimpleImpl }
+type reflectedBoolImpl struct{ *reflectedSimpleImpl }
+type reflectedNumberImpl struct{ *reflectedSimpleImpl }
+type reflectedTimestampImpl struct{ *reflectedSimpleImpl }
+type reflectedDurationImpl struct{ *reflectedSimpleImpl }
+type reflectedBytesImpl struct{ *reflectedSimpleImpl }
+
+var _ value.String = reflectedStringImpl{}
+var _ value.Bool = reflectedBoolImpl{}
+var _ value.ClassifiedNumber = reflectedNumberImpl{}
+var _ value.Timestamp = reflectedTimestampImpl{}
+var _ value.Duration = reflectedDurationImpl{}
+var _ value.Bytes = reflectedBytesImpl{}
+
+func newReflectedSimple(rValue reflect.Value, source value.Source, t *trail) value.Value {
+	o := &reflectedSimpleImpl{
+		rValue: rValue,
+		source: source,
+		trail:  t,
+	}
+	switch o.Kind() {
+	case value.StringKind:
+		return reflectedStringImpl{o}
+	case value.BoolKind:
+		return reflectedBoolImpl{o}
+	case value.NumberKind:
+		return reflectedNumberImpl{o}
+	case value.TimestampKind:
+		return reflectedTimestampImpl{o}
+	case value.DurationKind:
+		return reflectedDurationImpl{o}
+	case value.BytesKind:
+		return reflectedBytesImpl{o}
+	}
+	return o
+}
+
 func (o *reflectedSimpleImpl) Source() value.Source {
 	return o.source
 }
 func (o *reflectedSimpleImpl) Kind() value.Kind {
+	t := o.rValue.Type()
+	switch {
+	case t == timeType:
+		return value.TimestampKind
+	case t == durationType:
+		return value.DurationKind
+	case isBytesType(t):
+		return value.BytesKind
+	}
 	rk := o.rValue.Kind()
 	switch rk {
 	case reflect.String:
@@ -40,12 +91,127 @@ func (o *reflectedSimpleImpl) Interface() interface{} {
 	return o.rValue.Interface()
 }
 
+// native returns a detached copy of the value, which provides the conversions
+// and comparisons shared with natively constructed values.
+func (o *reflectedSimpleImpl) native() (value.Simple, error) {
+	switch o.Kind() {
+	case value.TimestampKind:
+		return value.NewTimestamp(o.rValue.Interface().(time.Time), o.source), nil
+	case value.DurationKind:
+		return value.NewDuration(time.Duration(o.rValue.Int()), o.source), nil
+	case value.BytesKind:
+		return value.NewBytes(o.rValue.Bytes(), o.source), nil
+	case value.StringKind:
+		return value.NewString(o.rValue.String(), o.source), nil
+	case value.BoolKind:
+		return value.NewBool(o.rValue.Bool(), o.source), nil
+	}
+	switch o.rValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.NewInt(o.rValue.Int(), o.source), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.NewUnsigned(o.rValue.Uint(), o.source), nil
+	case reflect.Float32:
+		return value.NewFloat(float32(o.rValue.Float()), o.source), nil
+	case reflect.Float64:
+		return value.NewFloat(o.rValue.Float(), o.source), nil
+	case reflect.Complex64:
+		return value.NewComplex(complex64(o.rValue.Complex()), o.source), nil
+	case reflect.Complex128:
+		return value.NewComplex(o.rValue.Complex(), o.source), nil
+	}
+	return nil, fmt.Errorf("no native representation for %s", o.Kind())
+}
+
+func (o *reflectedSimpleImpl) number() (value.Number, error) {
+	native, err := o.native()
+	if err != nil {
+		return nil, err
+	}
+	n, ok := native.(value.Number)
+	if !ok {
+		return nil, fmt.Errorf("expected number, but got %s", o.Kind())
+	}
+	return n, nil
+}
+
 func (o *reflectedSimpleImpl) String() string {
+	if native, err := o.native(); err == nil {
+		return native.String()
+	}
 	return fmt.Sprintf("%v", o.rValue.Interface())
 }
 
+func (o reflectedStringImpl) StringOrError() (string, error) {
+	return o.rValue.String(), nil
+}
+
+func (o reflectedBoolImpl) Bool() (bool, error) {
+	return o.rValue.Bool(), nil
+}
+
+func (o reflectedNumberImpl) NumberType() value.NumberType {
+	n, err := o.number()
+	if err != nil {
+		return value.UnknownNumber
+	}
+	return value.NumberTypeOf(n)
+}
+
+func (o reflectedNumberImpl) Int(bits int) (int64, error) {
+	n, err := o.number()
+	if err != nil {
+		return 0, err
+	}
+	return n.Int(bits)
+}
+
+func (o reflectedNumberImpl) Unsigned(bits int) (uint64, error) {
+	n, err := o.number()
+	if err != nil {
+		return 0, err
+	}
+	return n.Unsigned(bits)
+}
+
+func (o reflectedNumberImpl) Float(bits int) (float64, error) {
+	n, err := o.number()
+	if err != nil {
+		return 0, err
+	}
+	return n.Float(bits)
+}
+
+func (o reflectedNumberImpl) Complex(bits int) (complex128, error) {
+	n, err := o.number()
+	if err != nil {
+		return 0, err
+	}
+	return n.Complex(bits)
+}
+
+func (o reflectedNumberImpl) BigInt() (*big.Int, error) {
+	n, err := o.number()
+	if err != nil {
+		return nil, err
+	}
+	return value.BigIntOf(n)
+}
+
+func (o reflectedTimestampImpl) Time() (time.Time, error) {
+	return o.rValue.Interface().(time.Time), nil
+}
+
+func (o reflectedDurationImpl) Duration() (time.Duration, error) {
+	return time.Duration(o.rValue.Int()), nil
+}
+
+func (o reflectedBytesImpl) Bytes() ([]byte, error) {
+	return o.rValue.Bytes(), nil
+}
+
 func (o *reflectedSimpleImpl) SetValue(value value.Value) error {
-	return fmt.Errorf("not implemented - reflectedSimpleImpl.SetValue")
+	return assign(o.rValue, value)
 }
 
 func (o *reflectedSimpleImpl) WithoutSource() interface{} {
@@ -53,5 +219,9 @@ func (o *reflectedSimpleImpl) WithoutSource() interface{} {
 }
 
 func (o *reflectedSimpleImpl) CompareTo(other value.Simple) (int, error) {
-	return 0, fmt.Errorf("not implemented - reflectedSimpleImpl.CompareTo")
+	native, err := o.native()
+	if err != nil {
+		return 0, err
+	}
+	return native.CompareTo(other)
 }
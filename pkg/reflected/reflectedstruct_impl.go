@@ -11,6 +11,7 @@ import (
 type reflectedStructImpl struct {
 	rValue reflect.Value
 	source value.Source
+	trail  *trail
 }
 
 var _ value.ModifiableMap = &reflectedStructImpl{}
@@ -24,13 +25,33 @@ func (o *reflectedStructImpl) Kind() value.Kind {
 }
 
 func (o *reflectedStructImpl) Field(k key.Interface) (value.Value, error) {
-	rk := o.rValue.Kind()
-	return nil, fmt.Errorf("Field not supported for %s (%s)", o.Kind(), rk)
+	safeKey, ok := k.(key.Value[string])
+	if !ok {
+		return nil, fmt.Errorf("expected key.Value[string], but got %T", k)
+	}
+	fields := getStructFields(o.rValue.Type())
+	n, ok := fields.byName[safeKey.X]
+	if !ok {
+		return nil, fmt.Errorf("Field %q not found", k)
+	}
+	field := &fields.list[n]
+	child := field.fieldValue(o.rValue)
+	if !field.present(child) {
+		return nil, fmt.Errorf("Field %q not found", k)
+	}
+	return newReflected(child, o.trail.child(k, "."+field.goName))
 }
 
 func (o *reflectedStructImpl) Length() (int, error) {
-	rk := o.rValue.Kind()
-	return 0, fmt.Errorf("Length not supported for %s (%s)", o.Kind(), rk)
+	fields := getStructFields(o.rValue.Type())
+	length := 0
+	for n := range fields.list {
+		field := &fields.list[n]
+		if field.present(field.fieldValue(o.rValue)) {
+			length++
+		}
+	}
+	return length, nil
 }
 
 func (o *reflectedStructImpl) Interface() interface{} {
@@ -38,15 +59,44 @@ func (o *reflectedStructImpl) Interface() interface{} {
 }
 
 func (o *reflectedStructImpl) SetValue(value value.Value) error {
-	return fmt.Errorf("not implemented - SetValue")
+	return assign(o.rValue, value)
 }
 
-func (o *reflectedStructImpl) SetField(key key.Interface, value value.Value) error {
-	return fmt.Errorf("not implemented - SetField")
+func (o *reflectedStructImpl) SetField(k key.Interface, value value.Value) error {
+	safeKey, ok := k.(key.Value[string])
+	if !ok {
+		return fmt.Errorf("expected key.Value[string], but got %T", k)
+	}
+	fields := getStructFields(o.rValue.Type())
+	n, ok := fields.byName[safeKey.X]
+	if !ok {
+		return fmt.Errorf("Field %q not found", k)
+	}
+	field, err := fields.list[n].settableValue(o.rValue)
+	if err != nil {
+		return err
+	}
+	return assign(field, value)
 }
 
 func (o *reflectedStructImpl) ForEach(f func(index key.Interface, value value.Value) error) error {
-	return fmt.Errorf("not implemented - ForEach")
+	fields := getStructFields(o.rValue.Type())
+	for n := range fields.list {
+		field := &fields.list[n]
+		reflectedChild := field.fieldValue(o.rValue)
+		if !field.present(reflectedChild) {
+			continue
+		}
+		fieldKey := key.Value[string]{X: field.name}
+		child, err := newReflected(reflectedChild, o.trail.child(fieldKey, "."+field.goName))
+		if err != nil {
+			return err
+		}
+		if err = f(fieldKey, child); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 func (o *reflectedStructImpl) WithoutSource() interface{} {
 	return o.Interface()
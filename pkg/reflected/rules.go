@@ -0,0 +1,332 @@
+package reflected
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// rule is one of the comma separated rules in a validate struct tag, e.g.
+// `validate:"required,min=1,max=65535"`.
+type rule struct {
+	name     string
+	param    string
+	limit    *big.Rat
+	duration *time.Duration
+	options  []string
+	pattern  *regexp.Regexp
+}
+
+func (r *rule) String() string {
+	if r.param == "" {
+		return r.name
+	}
+	return r.name + "=" + r.param
+}
+
+// parseRules parses a validate tag. As a pattern may itself contain commas,
+// regexp must be the last rule.
+func parseRules(tag string) ([]rule, error) {
+	var rules []rule
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regexp=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		r := rule{name: name, param: param}
+		switch name {
+		case "required":
+			if param != "" {
+				return nil, fmt.Errorf("rule %s takes no parameter", name)
+			}
+		case "min", "max", "len":
+			if limit, ok := new(big.Rat).SetString(param); ok {
+				r.limit = limit
+			} else if d, err := time.ParseDuration(param); err == nil {
+				r.duration = &d
+			} else {
+				return nil, fmt.Errorf("rule %s expects a number or duration, but got %q", name, param)
+			}
+		case "oneof":
+			r.options = strings.Fields(param)
+			if len(r.options) == 0 {
+				return nil, fmt.Errorf("rule %s expects space separated options", name)
+			}
+		case "regexp":
+			pattern, err := regexp.Compile(param)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", name, err)
+			}
+			r.pattern = pattern
+		default:
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Violation is a value breaking a rule declared in a validate struct tag.
+// Expression is the go expression selecting the value and Source that of
+// the node it was decoded from, if known.
+type Violation struct {
+	Path       []key.Interface
+	Expression string
+	Rule       string
+	Message    string
+	Source     value.Source
+}
+
+func (v *Violation) String() string {
+	source := v.Source
+	if source == nil {
+		source = value.UnknownSource
+	}
+	return fmt.Sprintf("%s: %s %s (%s)", source.String(), v.Expression, v.Message, v.Rule)
+}
+
+type ErrValidation struct {
+	Violations []Violation
+}
+
+func (e *ErrValidation) Error() string {
+	if len(e.Violations) == 1 {
+		return e.Violations[0].String()
+	}
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "%d validation errors:", len(e.Violations))
+	for i := range e.Violations {
+		sb.WriteString("\n\t")
+		sb.WriteString(e.Violations[i].String())
+	}
+	return sb.String()
+}
+
+// Validate checks the rules in the validate tags of obj's struct fields,
+// including those of nested structs, slices and maps. Rules are evaluated over
+// the reflected view of obj. If obj was decoded from origin, violations give
+// the source of the originating node, or of its nearest ancestor if the value
+// was not present.
+//
+// The rules are required, which rejects zero values, min, max and len, which
+// limit numbers, durations and the lengths of strings and collections, oneof,
+// which takes space separated options, and regexp.
+func Validate(obj any, origin value.Value) error {
+	view, err := NewReflectedObject(reflect.ValueOf(obj), value.UnknownSource)
+	if err != nil {
+		return err
+	}
+	v := &validator{origin: origin}
+	if err := v.walk(view); err != nil {
+		return err
+	}
+	if len(v.violations) > 0 {
+		return &ErrValidation{Violations: v.violations}
+	}
+	return nil
+}
+
+type validator struct {
+	origin     value.Value
+	violations []Violation
+}
+
+func (v *validator) walk(node value.Value) error {
+	switch n := node.(type) {
+	case *reflectedStructImpl:
+		fields := getStructFields(n.rValue.Type())
+		for i := range fields.list {
+			field := &fields.list[i]
+			if field.rulesErr != nil {
+				return fmt.Errorf("invalid validate tag on %s.%s: %w", n.rValue.Type(), field.goName, field.rulesErr)
+			}
+			t := n.trail.child(key.Value[string]{X: field.name}, "."+field.goName)
+			rChild := field.fieldValue(n.rValue)
+			if !rChild.IsValid() {
+				v.check(nil, t, field.rules)
+				continue
+			}
+			child, err := newReflected(rChild, t)
+			if err != nil {
+				return err
+			}
+			v.check(child, t, field.rules)
+			if err := v.walk(child); err != nil {
+				return err
+			}
+		}
+	case *reflectedArrayImpl, *reflectedMapImpl:
+		return n.(value.Collection).ForEach(func(k key.Interface, child value.Value) error {
+			return v.walk(child)
+		})
+	}
+	return nil
+}
+
+// originSource returns the source of the node at p in the origin, or of its
+// nearest ancestor.
+func (v *validator) originSource(p []key.Interface) value.Source {
+	node := v.origin
+	for _, k := range p {
+		var child value.Value
+		var err error
+		switch c := node.(type) {
+		case value.Map:
+			child, err = c.Field(k)
+			if err != nil {
+				child, err = c.Field(key.Value[string]{X: value.KeyName(k)})
+			}
+		case value.Array:
+			child, err = c.Index(k)
+		default:
+			err = fmt.Errorf("expected map or array, but got %s", node.Kind())
+		}
+		if err != nil {
+			break
+		}
+		node = child
+	}
+	return node.Source()
+}
+
+func (v *validator) report(t *trail, r *rule, format string, args ...any) {
+	source := t.source()
+	var origin value.Source = source
+	if v.origin != nil {
+		origin = v.originSource(source.Path())
+	}
+	v.violations = append(v.violations, Violation{
+		Path:       source.Path(),
+		Expression: source.Expression(),
+		Rule:       r.String(),
+		Message:    fmt.Sprintf(format, args...),
+		Source:     origin,
+	})
+}
+
+func isZero(v value.Value) bool {
+	if v == nil || v.Kind() == value.NullKind {
+		return true
+	}
+	r, ok := v.(goValue)
+	if !ok {
+		return false
+	}
+	return isEmptyValue(reflect.ValueOf(r.Interface()))
+}
+
+func (v *validator) check(node value.Value, t *trail, rules []rule) {
+	for i := range rules {
+		r := &rules[i]
+		if r.name == "required" {
+			if isZero(node) {
+				v.report(t, r, "is required")
+			}
+			continue
+		}
+		if node == nil || node.Kind() == value.NullKind {
+			continue
+		}
+		switch r.name {
+		case "min", "max", "len":
+			v.checkLimit(node, t, r)
+		case "oneof":
+			s, ok := node.(value.Simple)
+			if !ok {
+				v.report(t, r, "cannot be compared to options")
+				continue
+			}
+			matched := false
+			for _, option := range r.options {
+				matched = matched || s.String() == option
+			}
+			if !matched {
+				v.report(t, r, "must be one of %s, but got %q", strings.Join(r.options, ", "), s.String())
+			}
+		case "regexp":
+			s, ok := node.(value.String)
+			if !ok || node.Kind() != value.StringKind {
+				v.report(t, r, "must be a string to match a pattern")
+				continue
+			}
+			text, err := s.StringOrError()
+			if err != nil || !r.pattern.MatchString(text) {
+				v.report(t, r, "%q does not match %q", text, r.pattern.String())
+			}
+		}
+	}
+}
+
+// measure returns the number rules compare with limits, and whether it is a
+// length rather than the value itself.
+func measure(node value.Value) (*big.Rat, bool, error) {
+	switch node.Kind() {
+	case value.NumberKind:
+		n, ok := node.(value.Number)
+		if ok && value.NumberTypeOf(n) != value.ComplexNumber {
+			if r, ok := new(big.Rat).SetString(n.String()); ok {
+				return r, false, nil
+			}
+		}
+	case value.DurationKind:
+		if d, ok := node.(value.Duration); ok {
+			duration, err := d.Duration()
+			return big.NewRat(int64(duration), 1), false, err
+		}
+	case value.StringKind:
+		if s, ok := node.(value.String); ok {
+			text, err := s.StringOrError()
+			return big.NewRat(int64(utf8.RuneCountInString(text)), 1), true, err
+		}
+	case value.BytesKind:
+		if b, ok := node.(value.Bytes); ok {
+			data, err := b.Bytes()
+			return big.NewRat(int64(len(data)), 1), true, err
+		}
+	case value.ArrayKind, value.MapKind:
+		if c, ok := node.(value.Collection); ok {
+			length, err := c.Length()
+			return big.NewRat(int64(length), 1), true, err
+		}
+	}
+	return nil, false, fmt.Errorf("%s has no size", node.Kind())
+}
+
+func (v *validator) checkLimit(node value.Value, t *trail, r *rule) {
+	actual, isLength, err := measure(node)
+	if err != nil {
+		v.report(t, r, "cannot be checked: %s", err)
+		return
+	}
+	limit := r.limit
+	if r.duration != nil {
+		if node.Kind() != value.DurationKind {
+			v.report(t, r, "must be a duration to compare with %s", r.param)
+			return
+		}
+		limit = big.NewRat(int64(*r.duration), 1)
+	}
+	what := ""
+	if isLength {
+		what = "length "
+	}
+	c := actual.Cmp(limit)
+	switch {
+	case r.name == "min" && c < 0:
+		v.report(t, r, "%smust be at least %s", what, r.param)
+	case r.name == "max" && c > 0:
+		v.report(t, r, "%smust be at most %s", what, r.param)
+	case r.name == "len" && c != 0:
+		v.report(t, r, "%smust be exactly %s", what, r.param)
+	}
+}
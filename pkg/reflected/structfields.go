@@ -0,0 +1,215 @@
+package reflected
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// tagNames lists the struct tags consulted for field names, in order of
+// precedence.
+var tagNames = []string{"dsvalue", "json", "yaml"}
+
+type structField struct {
+	name      string
+	goName    string
+	index     []int
+	omitEmpty bool
+	tagged    bool
+	rules     []rule
+	rulesErr  error
+}
+
+type structFields struct {
+	list   []structField
+	byName map[string]int
+}
+
+var structFieldCache sync.Map // map[reflect.Type]*structFields
+
+type fieldTag struct {
+	name      string
+	omitEmpty bool
+	inline    bool
+	skip      bool
+}
+
+func parseFieldTag(f reflect.StructField) (fieldTag, bool) {
+	for _, tagName := range tagNames {
+		tag, ok := f.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+		if tag == "-" {
+			return fieldTag{skip: true}, true
+		}
+		parts := strings.Split(tag, ",")
+		result := fieldTag{name: parts[0]}
+		for _, option := range parts[1:] {
+			switch option {
+			case "omitempty":
+				result.omitEmpty = true
+			case "inline", "squash":
+				result.inline = true
+			}
+		}
+		return result, true
+	}
+	return fieldTag{}, false
+}
+
+func getStructFields(t reflect.Type) *structFields {
+	if cached, ok := structFieldCache.Load(t); ok {
+		return cached.(*structFields)
+	}
+	var candidates []structField
+	collectStructFields(t, nil, &candidates, map[reflect.Type]bool{})
+	fields := dominantFields(candidates)
+	cached, _ := structFieldCache.LoadOrStore(t, fields)
+	return cached.(*structFields)
+}
+
+func collectStructFields(t reflect.Type, index []int, candidates *[]structField, visiting map[reflect.Type]bool) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, tagged := parseFieldTag(f)
+		if tag.skip {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+
+		embeddedType := f.Type
+		if embeddedType.Kind() == reflect.Ptr {
+			embeddedType = embeddedType.Elem()
+		}
+		if embeddedType.Kind() == reflect.Struct && !isSimpleType(embeddedType) {
+			if (tag.inline && (f.IsExported() || f.Anonymous)) || (f.Anonymous && tag.name == "") {
+				collectStructFields(embeddedType, fieldIndex, candidates, visiting)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name := tag.name
+		if name == "" {
+			name = f.Name
+		}
+		field := structField{
+			name:      name,
+			goName:    f.Name,
+			index:     fieldIndex,
+			omitEmpty: tag.omitEmpty,
+			tagged:    tagged && tag.name != "",
+		}
+		field.rules, field.rulesErr = parseRules(f.Tag.Get("validate"))
+		*candidates = append(*candidates, field)
+	}
+}
+
+// dominantFields resolves fields sharing a name as encoding/json does: the
+// shallowest wins, then the only tagged one at that depth, and if several
+// remain none of them is used. The result is in declaration order.
+func dominantFields(candidates []structField) *structFields {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := &candidates[i], &candidates[j]
+		switch {
+		case a.name != b.name:
+			return a.name < b.name
+		case len(a.index) != len(b.index):
+			return len(a.index) < len(b.index)
+		case a.tagged != b.tagged:
+			return a.tagged
+		}
+		return indexLess(a.index, b.index)
+	})
+	fields := &structFields{byName: make(map[string]int)}
+	for i := 0; i < len(candidates); {
+		j := i + 1
+		for j < len(candidates) && candidates[j].name == candidates[i].name {
+			j++
+		}
+		group := candidates[i:j]
+		if len(group) == 1 || len(group[0].index) < len(group[1].index) || group[0].tagged != group[1].tagged {
+			fields.list = append(fields.list, group[0])
+		}
+		i = j
+	}
+	sort.Slice(fields.list, func(i, j int) bool {
+		return indexLess(fields.list[i].index, fields.list[j].index)
+	})
+	for n := range fields.list {
+		fields.byName[fields.list[n].name] = n
+	}
+	return fields
+}
+
+func indexLess(a, b []int) bool {
+	for n := range a {
+		if n >= len(b) {
+			return false
+		}
+		if a[n] != b[n] {
+			return a[n] < b[n]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// fieldValue returns the value of the field, or an invalid value if it is
+// reached through a nil embedded pointer.
+func (f *structField) fieldValue(rValue reflect.Value) reflect.Value {
+	child, err := rValue.FieldByIndexErr(f.index)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return child
+}
+
+// settableValue returns the field of rValue, allocating any nil embedded
+// pointers on the way.
+func (f *structField) settableValue(rValue reflect.Value) (reflect.Value, error) {
+	for n, i := range f.index {
+		if n > 0 && rValue.Kind() == reflect.Ptr {
+			if rValue.IsNil() {
+				if !rValue.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot allocate %s - value is not addressable", rValue.Type())
+				}
+				rValue.Set(reflect.New(rValue.Type().Elem()))
+			}
+			rValue = rValue.Elem()
+		}
+		rValue = rValue.Field(i)
+	}
+	return rValue, nil
+}
+
+// present reports whether the field should be listed for the struct value.
+func (f *structField) present(child reflect.Value) bool {
+	if !child.IsValid() {
+		return false
+	}
+	return !f.omitEmpty || !isEmptyValue(child)
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package reflected
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// reference identifies the memory behind a pointer, map or slice. The type
+// is included as a struct and its first field share an address.
+type reference struct {
+	ptr    uintptr
+	length int
+	t      reflect.Type
+}
+
+// trail records how a reflected value was reached from the root object, so
+// that cycles can be detected, and carries the options it was created with.
+type trail struct {
+	parent     *trail
+	key        key.Interface
+	segment    string
+	references []reference
+	config     *config
+
+	// set on the root only
+	base     value.Source
+	typeName string
+}
+
+// child returns the trail for the child found at k. segment is the go
+// expression which selects it, such as ".Spec" or "[1]".
+func (t *trail) child(k key.Interface, segment string) *trail {
+	return &trail{parent: t, key: k, segment: segment, config: t.config}
+}
+
+func (t *trail) root() *trail {
+	for t.parent != nil {
+		t = t.parent
+	}
+	return t
+}
+
+// nameRoot records the type of the root object, once any pointers have been
+// followed. Unnamed types such as map[string]any are called "value".
+func (t *trail) nameRoot(rValue reflect.Value) {
+	if t.parent != nil || t.typeName != "" {
+		return
+	}
+	for (rValue.Kind() == reflect.Ptr || rValue.Kind() == reflect.Interface) && !rValue.IsNil() {
+		rValue = rValue.Elem()
+	}
+	rt := rValue.Type()
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	t.typeName = rt.Name()
+	if t.typeName == "" {
+		t.typeName = "value"
+	}
+}
+
+func (t *trail) source() *GoSource {
+	return &GoSource{trail: t}
+}
+
+func indexSegment(i int) string {
+	return fmt.Sprintf("[%d]", i)
+}
+
+func mapKeySegment(k reflect.Value) string {
+	if k.Kind() == reflect.Interface && !k.IsNil() {
+		k = k.Elem()
+	}
+	if k.Kind() == reflect.String {
+		return fmt.Sprintf("[%q]", k.String())
+	}
+	return fmt.Sprintf("[%v]", k.Interface())
+}
+
+func (t *trail) path() []key.Interface {
+	var p []key.Interface
+	for ; t != nil; t = t.parent {
+		if t.key != nil {
+			p = append(p, t.key)
+		}
+	}
+	for i, j := 0, len(p)-1; i < j; i, j = i+1, j-1 {
+		p[i], p[j] = p[j], p[i]
+	}
+	return p
+}
+
+// visit records that rValue has been reached, returning an error if it was
+// already reached by an ancestor.
+func (t *trail) visit(rValue reflect.Value) error {
+	var r reference
+	switch rValue.Kind() {
+	case reflect.Ptr, reflect.Map:
+		r = reference{ptr: rValue.Pointer(), t: rValue.Type()}
+	case reflect.Slice:
+		r = reference{ptr: rValue.Pointer(), length: rValue.Len(), t: rValue.Type()}
+	default:
+		return nil
+	}
+	if r.ptr == 0 {
+		return nil
+	}
+	for ancestor := t; ancestor != nil; ancestor = ancestor.parent {
+		for _, seen := range ancestor.references {
+			if seen == r {
+				return &ErrCycle{Path: t.path(), Type: rValue.Type()}
+			}
+		}
+	}
+	t.references = append(t.references, r)
+	return nil
+}
+
+// GoSource is the source of a reflected value. It names the go expression
+// which selects the value from the root object, e.g. Config.Spec.Ports[1].Name,
+// following the source the root object was reflected with.
+type GoSource struct {
+	trail *trail
+}
+
+// Base returns the source the root object was reflected with.
+func (s *GoSource) Base() value.Source {
+	return s.trail.root().base
+}
+
+// Path returns the path from the root object to the value.
+func (s *GoSource) Path() []key.Interface {
+	return s.trail.path()
+}
+
+// Expression returns the go expression which selects the value.
+func (s *GoSource) Expression() string {
+	var segments []string
+	for t := s.trail; t.parent != nil; t = t.parent {
+		segments = append(segments, t.segment)
+	}
+	sb := strings.Builder{}
+	sb.WriteString(s.trail.root().typeName)
+	for i := len(segments) - 1; i >= 0; i-- {
+		sb.WriteString(segments[i])
+	}
+	return sb.String()
+}
+
+func (s *GoSource) String() string {
+	base := s.Base()
+	if base == nil || base == value.UnknownSource {
+		return s.Expression()
+	}
+	return fmt.Sprintf("%s (%s)", base.String(), s.Expression())
+}
+
+// ErrCycle reports that following a pointer, map or slice leads back to one
+// of its own ancestors. Path locates the value which closes the cycle.
+type ErrCycle struct {
+	Path []key.Interface
+	Type reflect.Type
+}
+
+func (e *ErrCycle) Error() string {
+	sb := strings.Builder{}
+	if len(e.Path) == 0 {
+		sb.WriteString(".")
+	}
+	for _, k := range e.Path {
+		sb.WriteString(k.String())
+	}
+	return fmt.Sprintf("cycle detected at '%s' (%s)", sb.String(), e.Type)
+}
@@ -0,0 +1,651 @@
+package schema
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+type bound struct {
+	rat  *big.Rat
+	text string
+}
+
+type patternNode struct {
+	pattern *regexp.Regexp
+	node    *node
+}
+
+// node is a compiled schema. Absent keywords are left as nil.
+type node struct {
+	location string
+	source   value.Source
+	boolean  *bool
+
+	ref        *node
+	dynamicRef *node
+
+	types    []string
+	enum     []value.Value
+	constant value.Value
+
+	multipleOf, maximum, exclusiveMaximum, minimum, exclusiveMinimum *bound
+
+	maxLength, minLength *int
+	pattern              *regexp.Regexp
+
+	prefixItems              []*node
+	items, contains          *node
+	maxItems, minItems       *int
+	maxContains, minContains *int
+	uniqueItems              bool
+
+	properties                   map[string]*node
+	patternProperties            []patternNode
+	additionalProperties         *node
+	propertyNames                *node
+	required                     []string
+	dependentRequired            map[string][]string
+	dependentSchemas             map[string]*node
+	maxProperties, minProperties *int
+
+	allOf, anyOf, oneOf             []*node
+	not, ifNode, thenNode, elseNode *node
+
+	unevaluatedItems, unevaluatedProperties *node
+}
+
+// location identifies a subschema by the resource containing it and a JSON
+// pointer within that resource.
+type location struct {
+	resource string
+	pointer  string
+}
+
+type compiler struct {
+	baseURI   string
+	loader    Loader
+	preloaded map[string]value.Value
+	documents map[string]value.Value
+	anchors   map[string]location
+	nodes     map[string]*node
+}
+
+func newCompiler() *compiler {
+	return &compiler{
+		loader:    FileLoader,
+		preloaded: make(map[string]value.Value),
+		documents: make(map[string]value.Value),
+		anchors:   make(map[string]location),
+		nodes:     make(map[string]*node),
+	}
+}
+
+// keywords whose values are subschemas, grouped by how they hold them.
+var (
+	schemaKeywords = []string{
+		"items", "contains", "additionalProperties", "propertyNames", "not",
+		"if", "then", "else", "unevaluatedItems", "unevaluatedProperties",
+	}
+	schemaArrayKeywords = []string{"prefixItems", "allOf", "anyOf", "oneOf"}
+	schemaMapKeywords   = []string{"$defs", "definitions", "properties", "patternProperties", "dependentSchemas"}
+)
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func resolveURI(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(r).String(), nil
+}
+
+// splitFragment returns uri without its fragment, and the decoded fragment.
+func splitFragment(uri string) (string, string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", err
+	}
+	fragment := u.Fragment
+	u.Fragment, u.RawFragment = "", ""
+	return u.String(), fragment, nil
+}
+
+func stringField(m value.Map, name string) (string, bool) {
+	v, err := m.Field(key.Value[string]{X: name})
+	if err != nil || v.Kind() != value.StringKind {
+		return "", false
+	}
+	s, ok := v.(value.Simple)
+	if !ok {
+		return "", false
+	}
+	return s.String(), true
+}
+
+// resourceOf returns the URI of the resource v declares with $id, or base if
+// it does not.
+func (c *compiler) resourceOf(base string, v value.Value) string {
+	m, ok := v.(value.Map)
+	if !ok {
+		return base
+	}
+	id, ok := stringField(m, "$id")
+	if !ok {
+		return base
+	}
+	uri, err := resolveURI(base, id)
+	if err != nil {
+		return base
+	}
+	uri, _, err = splitFragment(uri)
+	if err != nil {
+		return base
+	}
+	return uri
+}
+
+// addDocument registers doc as uri and indexes the resources and anchors it
+// declares.
+func (c *compiler) addDocument(uri string, doc value.Value) error {
+	c.documents[uri] = doc
+	return c.index(uri, "", doc)
+}
+
+func (c *compiler) index(resource, pointer string, v value.Value) error {
+	m, ok := v.(value.Map)
+	if !ok || v.Kind() != value.MapKind {
+		return nil
+	}
+	if uri := c.resourceOf(resource, v); uri != resource {
+		c.documents[uri] = v
+		resource, pointer = uri, ""
+	}
+	for _, name := range []string{"$anchor", "$dynamicAnchor"} {
+		if anchor, ok := stringField(m, name); ok {
+			c.anchors[resource+"#"+anchor] = location{resource: resource, pointer: pointer}
+		}
+	}
+	for _, name := range schemaKeywords {
+		if child, err := m.Field(key.Value[string]{X: name}); err == nil {
+			if err := c.index(resource, pointer+"/"+name, child); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range schemaArrayKeywords {
+		if child, err := m.Field(key.Value[string]{X: name}); err == nil {
+			if a, ok := child.(value.Array); ok {
+				err := a.ForEach(func(k key.Interface, element value.Value) error {
+					return c.index(resource, fmt.Sprintf("%s/%s/%s", pointer, name, value.KeyName(k)), element)
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, name := range schemaMapKeywords {
+		if child, err := m.Field(key.Value[string]{X: name}); err == nil {
+			if schemas, ok := child.(value.Map); ok {
+				err := schemas.ForEach(func(k key.Interface, element value.Value) error {
+					return c.index(resource, pointer+"/"+name+"/"+escapePointer(value.KeyName(k)), element)
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRef compiles the subschema ref refers to, loading its document if
+// necessary.
+func (c *compiler) resolveRef(resource, ref string) (*node, error) {
+	uri, err := resolveURI(resource, ref)
+	if err != nil {
+		return nil, err
+	}
+	doc, fragment, err := splitFragment(uri)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := c.documents[doc]
+	if !ok {
+		root, err = c.loader(doc)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load %s: %w", doc, err)
+		}
+		if err := c.addDocument(doc, root); err != nil {
+			return nil, err
+		}
+	}
+	if fragment == "" || strings.HasPrefix(fragment, "/") {
+		target, err := path.EvaluatePointer(root, fragment)
+		if err != nil {
+			return nil, err
+		}
+		return c.compile(doc, fragment, target)
+	}
+	loc, ok := c.anchors[doc+"#"+fragment]
+	if !ok {
+		return nil, fmt.Errorf("anchor %q not found in %s", fragment, doc)
+	}
+	target, err := path.EvaluatePointer(c.documents[loc.resource], loc.pointer)
+	if err != nil {
+		return nil, err
+	}
+	return c.compile(loc.resource, loc.pointer, target)
+}
+
+// compile returns the node for the subschema v found at pointer within
+// resource. Nodes are cached before their keywords are compiled, so that
+// recursive schemas terminate.
+func (c *compiler) compile(resource, pointer string, v value.Value) (*node, error) {
+	if uri := c.resourceOf(resource, v); uri != resource {
+		resource, pointer = uri, ""
+	}
+	name := resource + "#" + pointer
+	if n, ok := c.nodes[name]; ok {
+		return n, nil
+	}
+	n := &node{location: name, source: v.Source()}
+	c.nodes[name] = n
+	switch v.Kind() {
+	case value.BoolKind:
+		b, ok := v.(value.Bool)
+		if !ok {
+			return nil, &ErrSchema{Location: name, Source: v.Source(), Inner: fmt.Errorf("expected bool, but got %T", v)}
+		}
+		allowed, err := b.Bool()
+		if err != nil {
+			return nil, &ErrSchema{Location: name, Source: v.Source(), Inner: err}
+		}
+		n.boolean = &allowed
+		return n, nil
+	case value.MapKind:
+		m, ok := v.(value.Map)
+		if ok {
+			k := &keywords{c: c, resource: resource, pointer: pointer, m: m}
+			k.compile(n)
+			if k.err != nil {
+				return nil, k.err
+			}
+			return n, nil
+		}
+	}
+	return nil, &ErrSchema{Location: name, Source: v.Source(), Inner: fmt.Errorf("expected object or boolean, but got %s", v.Kind())}
+}
+
+// inPlace returns the subschemas n applies to the same instance as itself.
+func (n *node) inPlace() []*node {
+	candidates := []*node{n.ref, n.dynamicRef, n.not, n.ifNode, n.thenNode, n.elseNode}
+	candidates = append(candidates, n.allOf...)
+	candidates = append(candidates, n.anyOf...)
+	candidates = append(candidates, n.oneOf...)
+	names := make([]string, 0, len(n.dependentSchemas))
+	for name := range n.dependentSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		candidates = append(candidates, n.dependentSchemas[name])
+	}
+	var result []*node
+	for _, candidate := range candidates {
+		if candidate != nil {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+// checkCycles reports a subschema which reaches itself, through $ref or
+// other keywords applying to the same instance, without descending into the
+// instance. Validating against it would never terminate.
+func (c *compiler) checkCycles() error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[*node]int)
+	var chain []*node
+	var visit func(n *node) error
+	visit = func(n *node) error {
+		switch state[n] {
+		case visiting:
+			var locations []string
+			for i := len(chain) - 1; i >= 0; i-- {
+				locations = append([]string{chain[i].location}, locations...)
+				if chain[i] == n {
+					break
+				}
+			}
+			locations = append(locations, n.location)
+			return &ErrSchema{Location: n.location, Source: n.source, Inner: fmt.Errorf("schema applies itself without consuming the instance: %s", strings.Join(locations, " -> "))}
+		case done:
+			return nil
+		}
+		state[n] = visiting
+		chain = append(chain, n)
+		for _, sub := range n.inPlace() {
+			if err := visit(sub); err != nil {
+				return err
+			}
+		}
+		chain = chain[:len(chain)-1]
+		state[n] = done
+		return nil
+	}
+	names := make([]string, 0, len(c.nodes))
+	for name := range c.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(c.nodes[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-------------------------------------------
+
+// keywords compiles the keywords of one schema object, keeping the first
+// error so that each keyword need not be checked individually.
+type keywords struct {
+	c        *compiler
+	resource string
+	pointer  string
+	m        value.Map
+	err      error
+}
+
+func (k *keywords) get(name string) (value.Value, bool) {
+	if k.err != nil {
+		return nil, false
+	}
+	v, err := k.m.Field(key.Value[string]{X: name})
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+func (k *keywords) fail(name string, v value.Value, err error) {
+	if k.err != nil || err == nil {
+		return
+	}
+	var source value.Source = value.UnknownSource
+	if v != nil {
+		source = v.Source()
+	}
+	k.err = &ErrSchema{Location: k.resource + "#" + k.pointer + "/" + escapePointer(name), Source: source, Inner: err}
+}
+
+func (k *keywords) subschema(pointer string, v value.Value) *node {
+	if k.err != nil {
+		return nil
+	}
+	n, err := k.c.compile(k.resource, k.pointer+pointer, v)
+	if err != nil {
+		k.err = err
+	}
+	return n
+}
+
+func (k *keywords) schema(name string) *node {
+	v, ok := k.get(name)
+	if !ok {
+		return nil
+	}
+	return k.subschema("/"+name, v)
+}
+
+func (k *keywords) schemaArray(name string) []*node {
+	v, ok := k.get(name)
+	if !ok {
+		return nil
+	}
+	a, ok := v.(value.Array)
+	if !ok || v.Kind() != value.ArrayKind {
+		k.fail(name, v, fmt.Errorf("expected array, but got %s", v.Kind()))
+		return nil
+	}
+	var nodes []*node
+	k.fail(name, v, a.ForEach(func(index key.Interface, element value.Value) error {
+		nodes = append(nodes, k.subschema(fmt.Sprintf("/%s/%s", name, value.KeyName(index)), element))
+		return nil
+	}))
+	if len(nodes) == 0 {
+		k.fail(name, v, fmt.Errorf("must not be empty"))
+	}
+	return nodes
+}
+
+func (k *keywords) forEachField(name string, f func(field string, v value.Value)) {
+	v, ok := k.get(name)
+	if !ok {
+		return
+	}
+	m, ok := v.(value.Map)
+	if !ok || v.Kind() != value.MapKind {
+		k.fail(name, v, fmt.Errorf("expected object, but got %s", v.Kind()))
+		return
+	}
+	k.fail(name, v, m.ForEach(func(field key.Interface, child value.Value) error {
+		f(value.KeyName(field), child)
+		return nil
+	}))
+}
+
+func (k *keywords) schemaMap(name string) map[string]*node {
+	var nodes map[string]*node
+	k.forEachField(name, func(field string, v value.Value) {
+		if nodes == nil {
+			nodes = make(map[string]*node)
+		}
+		nodes[field] = k.subschema("/"+name+"/"+escapePointer(field), v)
+	})
+	return nodes
+}
+
+func (k *keywords) number(name string) *bound {
+	v, ok := k.get(name)
+	if !ok {
+		return nil
+	}
+	r, ok := ratOf(v)
+	if !ok {
+		k.fail(name, v, fmt.Errorf("expected number, but got %s", v.Kind()))
+		return nil
+	}
+	return &bound{rat: r, text: v.(value.Simple).String()}
+}
+
+func (k *keywords) count(name string) *int {
+	v, ok := k.get(name)
+	if !ok {
+		return nil
+	}
+	r, ok := ratOf(v)
+	if !ok || !r.IsInt() || r.Sign() < 0 || !r.Num().IsInt64() {
+		k.fail(name, v, fmt.Errorf("expected a non-negative integer"))
+		return nil
+	}
+	n := int(r.Num().Int64())
+	return &n
+}
+
+func (k *keywords) string(name string) (string, value.Value, bool) {
+	v, ok := k.get(name)
+	if !ok {
+		return "", nil, false
+	}
+	s, ok := v.(value.Simple)
+	if !ok || v.Kind() != value.StringKind {
+		k.fail(name, v, fmt.Errorf("expected string, but got %s", v.Kind()))
+		return "", nil, false
+	}
+	return s.String(), v, true
+}
+
+func (k *keywords) strings(v value.Value, name string) []string {
+	a, ok := v.(value.Array)
+	if !ok || v.Kind() != value.ArrayKind {
+		k.fail(name, v, fmt.Errorf("expected array of strings, but got %s", v.Kind()))
+		return nil
+	}
+	var result []string
+	k.fail(name, v, a.ForEach(func(index key.Interface, element value.Value) error {
+		s, ok := element.(value.Simple)
+		if !ok || element.Kind() != value.StringKind {
+			return fmt.Errorf("expected string, but got %s", element.Kind())
+		}
+		result = append(result, s.String())
+		return nil
+	}))
+	return result
+}
+
+func (k *keywords) regexp(name string, pattern string, v value.Value) *regexp.Regexp {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		k.fail(name, v, err)
+		return nil
+	}
+	return re
+}
+
+func (k *keywords) ref(name string) *node {
+	ref, v, ok := k.string(name)
+	if !ok {
+		return nil
+	}
+	n, err := k.c.resolveRef(k.resource, ref)
+	if err != nil {
+		k.fail(name, v, err)
+	}
+	return n
+}
+
+var typeNames = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+func (k *keywords) compile(n *node) {
+	n.ref = k.ref("$ref")
+	n.dynamicRef = k.ref("$dynamicRef")
+
+	if v, ok := k.get("type"); ok {
+		if s, ok := v.(value.Simple); ok && v.Kind() == value.StringKind {
+			n.types = []string{s.String()}
+		} else {
+			n.types = k.strings(v, "type")
+		}
+		for _, t := range n.types {
+			if !typeNames[t] {
+				k.fail("type", v, fmt.Errorf("unknown type %q", t))
+			}
+		}
+	}
+	if v, ok := k.get("enum"); ok {
+		a, ok := v.(value.Array)
+		if !ok || v.Kind() != value.ArrayKind {
+			k.fail("enum", v, fmt.Errorf("expected array, but got %s", v.Kind()))
+		} else {
+			k.fail("enum", v, a.ForEach(func(index key.Interface, element value.Value) error {
+				n.enum = append(n.enum, element)
+				return nil
+			}))
+		}
+	}
+	if v, ok := k.get("const"); ok {
+		n.constant = v
+	}
+
+	n.multipleOf = k.number("multipleOf")
+	if n.multipleOf != nil && n.multipleOf.rat.Sign() <= 0 {
+		k.fail("multipleOf", nil, fmt.Errorf("must be greater than 0"))
+	}
+	n.maximum = k.number("maximum")
+	n.exclusiveMaximum = k.number("exclusiveMaximum")
+	n.minimum = k.number("minimum")
+	n.exclusiveMinimum = k.number("exclusiveMinimum")
+
+	n.maxLength = k.count("maxLength")
+	n.minLength = k.count("minLength")
+	if pattern, v, ok := k.string("pattern"); ok {
+		n.pattern = k.regexp("pattern", pattern, v)
+	}
+
+	n.prefixItems = k.schemaArray("prefixItems")
+	if v, ok := k.get("items"); ok && v.Kind() == value.ArrayKind {
+		k.fail("items", v, fmt.Errorf("an array of schemas is not allowed in 2020-12, use prefixItems"))
+	}
+	n.items = k.schema("items")
+	n.contains = k.schema("contains")
+	n.maxItems = k.count("maxItems")
+	n.minItems = k.count("minItems")
+	n.maxContains = k.count("maxContains")
+	n.minContains = k.count("minContains")
+	if v, ok := k.get("uniqueItems"); ok {
+		b, ok := v.(value.Bool)
+		if !ok || v.Kind() != value.BoolKind {
+			k.fail("uniqueItems", v, fmt.Errorf("expected bool, but got %s", v.Kind()))
+		} else {
+			n.uniqueItems, _ = b.Bool()
+		}
+	}
+
+	n.properties = k.schemaMap("properties")
+	k.forEachField("patternProperties", func(pattern string, v value.Value) {
+		re := k.regexp("patternProperties", pattern, v)
+		sub := k.subschema("/patternProperties/"+escapePointer(pattern), v)
+		if re != nil && sub != nil {
+			n.patternProperties = append(n.patternProperties, patternNode{pattern: re, node: sub})
+		}
+	})
+	n.additionalProperties = k.schema("additionalProperties")
+	n.propertyNames = k.schema("propertyNames")
+	if v, ok := k.get("required"); ok {
+		n.required = k.strings(v, "required")
+	}
+	k.forEachField("dependentRequired", func(field string, v value.Value) {
+		if n.dependentRequired == nil {
+			n.dependentRequired = make(map[string][]string)
+		}
+		n.dependentRequired[field] = k.strings(v, "dependentRequired")
+	})
+	n.dependentSchemas = k.schemaMap("dependentSchemas")
+	n.maxProperties = k.count("maxProperties")
+	n.minProperties = k.count("minProperties")
+
+	n.allOf = k.schemaArray("allOf")
+	n.anyOf = k.schemaArray("anyOf")
+	n.oneOf = k.schemaArray("oneOf")
+	n.not = k.schema("not")
+	n.ifNode = k.schema("if")
+	n.thenNode = k.schema("then")
+	n.elseNode = k.schema("else")
+
+	n.unevaluatedItems = k.schema("unevaluatedItems")
+	n.unevaluatedProperties = k.schema("unevaluatedProperties")
+}
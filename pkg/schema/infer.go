@@ -0,0 +1,229 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+type InferOption func(*inferConfig) error
+
+type inferConfig struct {
+	maxEnum int
+}
+
+// WithMaxEnum sets the most distinct values a string may take for an enum to
+// be inferred. The default is 10, and 0 disables enums.
+func WithMaxEnum(n int) InferOption {
+	return func(c *inferConfig) error {
+		if n < 0 {
+			return fmt.Errorf("invalid maximum enum size: %d", n)
+		}
+		c.maxEnum = n
+		return nil
+	}
+}
+
+// shape accumulates what has been seen at one path of the samples, with every
+// array index treated as the same path.
+type shape struct {
+	source     value.Source
+	count      int
+	objects    int
+	types      map[string]bool
+	timestamps int
+	strings    map[string]bool
+	strs       int
+	overflow   bool
+	minimum    *bound
+	maximum    *bound
+	properties map[string]*shape
+	items      *shape
+}
+
+func newShape(source value.Source) *shape {
+	return &shape{source: source, types: make(map[string]bool)}
+}
+
+func (s *shape) property(name string, source value.Source) *shape {
+	if s.properties == nil {
+		s.properties = make(map[string]*shape)
+	}
+	child, ok := s.properties[name]
+	if !ok {
+		child = newShape(source)
+		s.properties[name] = child
+	}
+	return child
+}
+
+func (s *shape) item(source value.Source) *shape {
+	if s.items == nil {
+		s.items = newShape(source)
+	}
+	return s.items
+}
+
+func (s *shape) record(v value.Value, c *inferConfig) {
+	s.count++
+	t := jsonType(v)
+	s.types[t] = true
+	switch t {
+	case "object":
+		s.objects++
+	case "integer", "number":
+		r, _ := ratOf(v)
+		text := v.(value.Simple).String()
+		if s.minimum == nil || r.Cmp(s.minimum.rat) < 0 {
+			s.minimum = &bound{rat: r, text: text}
+		}
+		if s.maximum == nil || r.Cmp(s.maximum.rat) > 0 {
+			s.maximum = &bound{rat: r, text: text}
+		}
+	case "string":
+		if v.Kind() == value.TimestampKind {
+			s.timestamps++
+			return
+		}
+		s.strs++
+		if s.overflow {
+			return
+		}
+		if s.strings == nil {
+			s.strings = make(map[string]bool)
+		}
+		s.strings[v.(value.Simple).String()] = true
+		if len(s.strings) > c.maxEnum {
+			s.strings, s.overflow = nil, true
+		}
+	}
+}
+
+// Infer returns a JSON Schema which all the samples conform to: the types seen
+// at each path, the properties present in every sample object as required,
+// the range of numbers, and the item shape of arrays. Strings with few
+// distinct values, at least one of which repeats, are given an enum.
+func Infer(samples []value.Value, options ...InferOption) (value.Value, error) {
+	c := &inferConfig{maxEnum: 10}
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, err
+		}
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("cannot infer a schema without samples")
+	}
+	var root *shape
+	for _, sample := range samples {
+		if root == nil {
+			root = newShape(sample.Source())
+		}
+		// stack holds the shapes of the collections enclosing the visited
+		// value, and kinds whether each is an array or map.
+		var stack []*shape
+		var kinds []value.Kind
+		err := path.Walk(sample, func(p path.Path, v value.Value, vt path.VisitType) error {
+			if vt == path.AtCollectionEnd {
+				return nil
+			}
+			depth := len(p)
+			s := root
+			if depth > 0 {
+				parent := stack[depth-1]
+				if kinds[depth-1] == value.ArrayKind {
+					s = parent.item(v.Source())
+				} else {
+					s = parent.property(value.KeyName(p[depth-1]), v.Source())
+				}
+			}
+			s.record(v, c)
+			if vt == path.AtCollectionStart {
+				stack = append(stack[:depth], s)
+				kinds = append(kinds[:depth], v.Kind())
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	schema := root.schema(c)
+	elements := schema.elements
+	elements["$schema"] = value.NewString("https://json-schema.org/draft/2020-12/schema", schema.source)
+	return value.NewMap(elements, schema.source), nil
+}
+
+type inferred struct {
+	elements map[string]value.Value
+	source   value.Source
+}
+
+func (s *shape) schema(c *inferConfig) *inferred {
+	source := s.source
+	elements := make(map[string]value.Value)
+	var types []string
+	for t := range s.types {
+		if t == "integer" && s.types["number"] {
+			continue
+		}
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	if len(types) == 1 {
+		elements["type"] = value.NewString(types[0], source)
+	} else {
+		var names []value.Value
+		for _, t := range types {
+			names = append(names, value.NewString(t, source))
+		}
+		elements["type"] = value.NewArray(names, source)
+	}
+
+	if s.minimum != nil {
+		elements["minimum"] = value.NewNumber(s.minimum.text, source)
+		elements["maximum"] = value.NewNumber(s.maximum.text, source)
+	}
+	if s.timestamps > 0 && s.strs == 0 {
+		elements["format"] = value.NewString("date-time", source)
+	}
+	if len(types) == 1 && s.types["string"] && s.strs > 0 && s.timestamps == 0 && !s.overflow && len(s.strings) < s.strs {
+		var values []string
+		for v := range s.strings {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		var enum []value.Value
+		for _, v := range values {
+			enum = append(enum, value.NewString(v, source))
+		}
+		elements["enum"] = value.NewArray(enum, source)
+	}
+
+	if s.objects > 0 {
+		properties := make(map[string]value.Value)
+		var required []string
+		for name, child := range s.properties {
+			property := child.schema(c)
+			properties[name] = value.NewMap(property.elements, property.source)
+			if child.count == s.objects {
+				required = append(required, name)
+			}
+		}
+		elements["properties"] = value.NewMap(properties, source)
+		if len(required) > 0 {
+			sort.Strings(required)
+			var names []value.Value
+			for _, name := range required {
+				names = append(names, value.NewString(name, source))
+			}
+			elements["required"] = value.NewArray(names, source)
+		}
+	}
+	if s.items != nil {
+		items := s.items.schema(c)
+		elements["items"] = value.NewMap(items.elements, items.source)
+	}
+	return &inferred{elements: elements, source: source}
+}
@@ -0,0 +1,201 @@
+// Package schema validates value trees against JSON Schema 2020-12 documents,
+// reporting each violation with the path and source of the offending node.
+package schema
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	dsformat "github.com/davidjspooner/dsvalue/pkg/format"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// Loader returns the schema document at uri, which has no fragment.
+type Loader func(uri string) (value.Value, error)
+
+type Option func(*compiler) error
+
+// WithBaseURI sets the URI relative $refs in the root document are resolved
+// against. A plain file name is converted to a file URI.
+func WithBaseURI(uri string) Option {
+	return func(c *compiler) error {
+		base, err := toURI(uri)
+		if err != nil {
+			return err
+		}
+		c.baseURI = base
+		return nil
+	}
+}
+
+// WithLoader replaces FileLoader as the way referenced documents are loaded.
+func WithLoader(loader Loader) Option {
+	return func(c *compiler) error {
+		if loader == nil {
+			return fmt.Errorf("loader must not be nil")
+		}
+		c.loader = loader
+		return nil
+	}
+}
+
+// WithDocument makes doc available to $ref as uri without loading it.
+func WithDocument(uri string, doc value.Value) Option {
+	return func(c *compiler) error {
+		absolute, err := toURI(uri)
+		if err != nil {
+			return err
+		}
+		c.preloaded[absolute] = doc
+		return nil
+	}
+}
+
+func toURI(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		return s, nil
+	}
+	absolute, err := filepath.Abs(s)
+	if err != nil {
+		return "", err
+	}
+	uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(absolute)}).String()
+	if strings.HasSuffix(s, string(filepath.Separator)) || strings.HasSuffix(s, "/") {
+		uri += "/"
+	}
+	return uri, nil
+}
+
+// FileLoader loads documents from file URIs using the format registered with
+// dsformat for their extension. Files without a registered format are
+// rejected; use WithLoader to load them some other way.
+func FileLoader(uri string) (value.Value, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "file" {
+		return nil, fmt.Errorf("cannot load %s: only local files are supported", uri)
+	}
+	name := filepath.FromSlash(u.Path)
+	if _, err := dsformat.ForFile(name); err != nil {
+		return nil, fmt.Errorf("cannot load %s: %w", name, err)
+	}
+	return dsformat.DecodeFile(name)
+}
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	root *node
+}
+
+// Compile compiles the schema doc, loading any documents it refers to.
+//
+// All the 2020-12 validation and applicator keywords are supported. format
+// is treated as an annotation, and $dynamicRef is resolved like $ref.
+func Compile(doc value.Value, options ...Option) (*Schema, error) {
+	c := newCompiler()
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.baseURI == "" {
+		cwd, err := toURI("." + string(filepath.Separator))
+		if err != nil {
+			return nil, err
+		}
+		c.baseURI = cwd
+	}
+	for uri, preloaded := range c.preloaded {
+		if err := c.addDocument(uri, preloaded); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.addDocument(c.baseURI, doc); err != nil {
+		return nil, err
+	}
+	root, err := c.compile(c.resourceOf(c.baseURI, doc), "", doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkCycles(); err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate returns an *ErrValidation listing every way v violates the
+// schema, or nil if it conforms.
+func (s *Schema) Validate(v value.Value) error {
+	violations := s.Violations(v)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ErrValidation{Violations: violations}
+}
+
+func (s *Schema) Violations(v value.Value) []Violation {
+	violations, _ := s.root.validate(v, path.Path{})
+	return violations
+}
+
+//-------------------------------------------
+
+// Violation is one way a value does not conform to a schema. Source is that
+// of the offending node.
+type Violation struct {
+	Path    path.Path
+	Keyword string
+	Message string
+	Source  value.Source
+}
+
+func (v *Violation) String() string {
+	source := v.Source
+	if source == nil {
+		source = value.UnknownSource
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", source.String(), v.Path.String(), v.Message, v.Keyword)
+}
+
+type ErrValidation struct {
+	Violations []Violation
+}
+
+func (e *ErrValidation) Error() string {
+	if len(e.Violations) == 1 {
+		return e.Violations[0].String()
+	}
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "%d schema violations:", len(e.Violations))
+	for i := range e.Violations {
+		sb.WriteString("\n\t")
+		sb.WriteString(e.Violations[i].String())
+	}
+	return sb.String()
+}
+
+// ErrSchema reports a schema which cannot be compiled. Location is the
+// absolute URI of the offending subschema.
+type ErrSchema struct {
+	Location string
+	Source   value.Source
+	Inner    error
+}
+
+func (e *ErrSchema) Error() string {
+	source := e.Source
+	if source == nil {
+		source = value.UnknownSource
+	}
+	return fmt.Sprintf("invalid schema %s at %s: %s", e.Location, source.String(), e.Inner)
+}
+
+func (e *ErrSchema) Unwrap() error {
+	return e.Inner
+}
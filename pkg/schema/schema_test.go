@@ -0,0 +1,268 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	dsformat "github.com/davidjspooner/dsvalue/pkg/format"
+	"github.com/davidjspooner/dsvalue/pkg/reflected"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+func mustFromGo(t *testing.T, obj any) value.Value {
+	v, err := reflected.FromGo(obj, value.UnknownSource)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return v
+}
+
+// testFormat decodes JSON without positions, so that FileLoader can load the
+// schema files written by the tests.
+type testFormat struct{}
+
+func (f *testFormat) Description() string {
+	return "test JSON"
+}
+func (f *testFormat) NewWithOptions(options ...dsformat.FormatOption) (dsformat.Interface, error) {
+	return f, nil
+}
+func (f *testFormat) NewEncoder(writer io.Writer, options ...dsformat.FormatOption) (dsformat.Encoder, error) {
+	return nil, errors.ErrUnsupported
+}
+func (f *testFormat) NewDecoder(reader io.Reader, options ...dsformat.FormatOption) (dsformat.Decoder, error) {
+	return &testDecoder{reader}, nil
+}
+
+type testDecoder struct {
+	reader io.Reader
+}
+
+func (d *testDecoder) Decode(target value.Source) error {
+	_, err := d.DecodeFrom(target)
+	return err
+}
+
+func (d *testDecoder) DecodeFrom(source value.Source) (value.Value, error) {
+	var obj any
+	decoder := json.NewDecoder(d.reader)
+	decoder.UseNumber()
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return reflected.FromGo(obj, source)
+}
+
+func init() {
+	dsformat.Register("test-json", &testFormat{}, ".json")
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "defs.json"), []byte(`{
+  "$defs": {
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535}
+  }
+}`), 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	doc := mustFromGo(t, map[string]any{
+		"$schema":  "https://json-schema.org/draft/2020-12/schema",
+		"type":     "object",
+		"required": []any{"name", "ports"},
+		"properties": map[string]any{
+			"name":  map[string]any{"type": "string", "pattern": "^[a-z]+$"},
+			"level": map[string]any{"enum": []any{"debug", "info"}},
+			"ports": map[string]any{
+				"type":  "array",
+				"items": map[string]any{"$ref": "#/$defs/namedPort"},
+			},
+		},
+		"additionalProperties": false,
+		"$defs": map[string]any{
+			"namedPort": map[string]any{
+				"type":                  "object",
+				"properties":            map[string]any{"port": map[string]any{"$ref": "defs.json#/$defs/port"}},
+				"unevaluatedProperties": false,
+			},
+		},
+	})
+	s, err := Compile(doc, WithBaseURI(filepath.Join(dir, "service.json")))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	valid := mustFromGo(t, map[string]any{
+		"name":  "web",
+		"level": "info",
+		"ports": []any{map[string]any{"port": 80}},
+	})
+	if err := s.Validate(valid); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	invalid := mustFromGo(t, map[string]any{
+		"name":  "Web",
+		"level": "trace",
+		"ports": []any{map[string]any{"port": 70000, "extra": true}},
+		"other": 1,
+	})
+	err = s.Validate(invalid)
+	var validationErr *ErrValidation
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ErrValidation, but got %v", err)
+	}
+	expected := map[string]string{
+		".level":          "enum",
+		".name":           "pattern",
+		".other":          "additionalProperties",
+		".ports[0].extra": "unevaluatedProperties",
+		".ports[0].port":  "maximum",
+	}
+	if len(validationErr.Violations) != len(expected) {
+		t.Errorf("expected %d violations, but got %s", len(expected), err)
+	}
+	for _, v := range validationErr.Violations {
+		if expected[v.Path.String()] != v.Keyword {
+			t.Errorf("unexpected violation %s", v.String())
+		}
+		if v.Source == nil || v.Source == value.UnknownSource {
+			t.Errorf("%s: expected a source", v.Path.String())
+		}
+	}
+}
+
+func TestFileLoader(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "defs.json"), []byte(`{
+  "$defs": {
+    "name": {"type": "string", "minLength": -1}
+  }
+}`), 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	doc := mustFromGo(t, map[string]any{"$ref": "defs.json#/$defs/name"})
+	_, err = Compile(doc, WithBaseURI(filepath.Join(dir, "service.json")))
+	var schemaErr *ErrSchema
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected ErrSchema, but got %v", err)
+	}
+	// the error at the $ref wraps the one in the referenced document
+	var inner *ErrSchema
+	for errors.As(schemaErr.Inner, &inner) {
+		schemaErr = inner
+	}
+	if schemaErr.Source == nil || !strings.Contains(schemaErr.Source.String(), "defs.json") {
+		t.Errorf("expected a source in defs.json, but got %v", schemaErr.Source)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "defs.unknown"), []byte(`{}`), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, err = FileLoader("file://" + filepath.ToSlash(filepath.Join(dir, "defs.unknown")))
+	if err == nil {
+		t.Errorf("expected an error loading a file without a registered format")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []map[string]any{
+		{"type": "text"},
+		{"$ref": "#/$defs/missing"},
+		{"minLength": -1},
+		{"items": []any{true}},
+	}
+	for _, test := range tests {
+		if _, err := Compile(mustFromGo(t, test)); err == nil {
+			t.Errorf("%v: expected an error", test)
+		}
+	}
+
+	cycles := []map[string]any{
+		{"$ref": "#"},
+		{"$ref": "#/$defs/a", "$defs": map[string]any{"a": map[string]any{"$ref": "#/$defs/b"}, "b": map[string]any{"$ref": "#/$defs/a"}}},
+		{"allOf": []any{map[string]any{"not": map[string]any{"$ref": "#"}}}},
+	}
+	for _, test := range cycles {
+		_, err := Compile(mustFromGo(t, test))
+		if err == nil || !strings.Contains(err.Error(), "without consuming the instance") {
+			t.Errorf("%v: expected a cycle error, got %v", test, err)
+		}
+	}
+	recursive := mustFromGo(t, map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"child": map[string]any{"$ref": "#"}},
+	})
+	s, err := Compile(recursive)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	nested := mustFromGo(t, map[string]any{"child": map[string]any{"child": "x"}})
+	if err := s.Validate(nested); err == nil {
+		t.Errorf("expected nested string to be rejected")
+	}
+}
+
+func TestInfer(t *testing.T) {
+	samples := []value.Value{
+		mustFromGo(t, map[string]any{
+			"name":  "web",
+			"level": "info",
+			"ports": []any{map[string]any{"port": 80}, map[string]any{"port": 443, "tls": true}},
+		}),
+		mustFromGo(t, map[string]any{
+			"name":  "db",
+			"level": "info",
+			"ports": []any{map[string]any{"port": 5432}},
+			"ratio": 0.5,
+		}),
+		mustFromGo(t, map[string]any{
+			"name":  "cache",
+			"level": "debug",
+			"ports": []any{},
+			"ratio": 2,
+		}),
+	}
+	inferred, err := Infer(samples)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s, err := Compile(inferred)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, sample := range samples {
+		if err := s.Validate(sample); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	}
+	schema := inferred.WithoutSource().(map[string]any)
+	properties := schema["properties"].(map[string]any)
+	tests := []struct {
+		actual, expected any
+	}{
+		{schema["required"], []any{"level", "name", "ports"}},
+		{properties["level"].(map[string]any)["enum"], []any{"debug", "info"}},
+		{properties["name"].(map[string]any)["enum"], nil},
+		{properties["ratio"].(map[string]any)["type"], "number"},
+		{properties["ports"].(map[string]any)["items"].(map[string]any)["required"], []any{"port"}},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.actual, test.expected) {
+			t.Errorf("expected %v, but got %v", test.expected, test.actual)
+		}
+	}
+
+	invalid := mustFromGo(t, map[string]any{"name": "x", "level": "trace", "ports": []any{map[string]any{"port": 99999}}})
+	if err := s.Validate(invalid); err == nil {
+		t.Errorf("expected a violation")
+	}
+}
@@ -0,0 +1,506 @@
+package schema
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+	"github.com/davidjspooner/dsvalue/pkg/path"
+	"github.com/davidjspooner/dsvalue/pkg/value"
+)
+
+// evaluated records the properties and items a schema evaluated successfully,
+// which unevaluatedProperties and unevaluatedItems then skip.
+type evaluated struct {
+	properties map[string]bool
+	items      map[int]bool
+	allItems   bool
+}
+
+func (e *evaluated) property(name string) {
+	if e.properties == nil {
+		e.properties = make(map[string]bool)
+	}
+	e.properties[name] = true
+}
+
+func (e *evaluated) item(index int) {
+	if e.items == nil {
+		e.items = make(map[int]bool)
+	}
+	e.items[index] = true
+}
+
+func (e *evaluated) merge(other *evaluated) {
+	if other == nil {
+		return
+	}
+	for name := range other.properties {
+		e.property(name)
+	}
+	for index := range other.items {
+		e.item(index)
+	}
+	e.allItems = e.allItems || other.allItems
+}
+
+func ratOf(v value.Value) (*big.Rat, bool) {
+	if v.Kind() != value.NumberKind {
+		return nil, false
+	}
+	n, ok := v.(value.Number)
+	if !ok || value.NumberTypeOf(n) == value.ComplexNumber {
+		return nil, false
+	}
+	if d, ok := v.(value.Decimal); ok {
+		return d.Rat(), true
+	}
+	return new(big.Rat).SetString(n.String())
+}
+
+// jsonType returns the JSON type of v. Timestamps, durations and bytes are
+// strings in JSON.
+func jsonType(v value.Value) string {
+	switch v.Kind() {
+	case value.NullKind:
+		return "null"
+	case value.BoolKind:
+		return "boolean"
+	case value.NumberKind:
+		if r, ok := ratOf(v); ok {
+			if r.IsInt() {
+				return "integer"
+			}
+			return "number"
+		}
+	case value.StringKind, value.TimestampKind, value.DurationKind, value.BytesKind:
+		return "string"
+	case value.ArrayKind:
+		return "array"
+	case value.MapKind:
+		return "object"
+	}
+	return v.Kind().String()
+}
+
+func hasType(v value.Value, t string) bool {
+	actual := jsonType(v)
+	return actual == t || (t == "number" && actual == "integer")
+}
+
+func equal(left, right value.Value) bool {
+	c, err := value.Compare(left, right)
+	return err == nil && c == 0
+}
+
+type field struct {
+	name  string
+	value value.Value
+}
+
+func sortedFields(m value.Map) ([]field, error) {
+	var fields []field
+	err := m.ForEach(func(k key.Interface, v value.Value) error {
+		fields = append(fields, field{value.KeyName(k), v})
+		return nil
+	})
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].name < fields[j].name
+	})
+	return fields, err
+}
+
+func elements(a value.Array) ([]value.Value, error) {
+	var result []value.Value
+	err := a.ForEach(func(k key.Interface, v value.Value) error {
+		result = append(result, v)
+		return nil
+	})
+	return result, err
+}
+
+func childPath(p path.Path, k key.Interface) path.Path {
+	child := make(path.Path, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, k)
+}
+
+// report collects the violations found while validating one node.
+type report struct {
+	v          value.Value
+	p          path.Path
+	violations []Violation
+}
+
+func (r *report) add(keyword string, format string, args ...any) {
+	r.violations = append(r.violations, Violation{
+		Path:    r.p,
+		Keyword: keyword,
+		Message: fmt.Sprintf(format, args...),
+		Source:  r.v.Source(),
+	})
+}
+
+// child validates v at p against n. A false schema is reported against
+// keyword, which is more helpful than naming the false schema itself.
+// Children are marked as evaluated even if they fail, so that
+// unevaluatedProperties and unevaluatedItems do not report them again.
+func (r *report) child(keyword string, n *node, v value.Value, p path.Path, what string) {
+	if n.boolean != nil && !*n.boolean {
+		r.violations = append(r.violations, Violation{
+			Path:    p,
+			Keyword: keyword,
+			Message: what + " is not allowed",
+			Source:  v.Source(),
+		})
+		return
+	}
+	violations, _ := n.validate(v, p)
+	r.violations = append(r.violations, violations...)
+}
+
+// validate checks v, found at p, against n. It returns what was evaluated for
+// the benefit of the unevaluated keywords of enclosing schemas.
+func (n *node) validate(v value.Value, p path.Path) ([]Violation, *evaluated) {
+	r := &report{v: v, p: p}
+	ev := &evaluated{}
+	if n.boolean != nil {
+		if !*n.boolean {
+			r.add("false", "no value is allowed")
+		}
+		return r.violations, ev
+	}
+	for _, ref := range []*node{n.ref, n.dynamicRef} {
+		if ref != nil {
+			violations, refEv := ref.validate(v, p)
+			r.violations = append(r.violations, violations...)
+			if len(violations) == 0 {
+				ev.merge(refEv)
+			}
+		}
+	}
+	n.validateAny(r)
+	switch v.Kind() {
+	case value.NumberKind:
+		n.validateNumber(r)
+	case value.ArrayKind:
+		if a, ok := v.(value.Array); ok {
+			n.validateArray(r, a, ev)
+		}
+	case value.MapKind:
+		if m, ok := v.(value.Map); ok {
+			n.validateObject(r, m, ev)
+		}
+	default:
+		if jsonType(v) == "string" {
+			n.validateString(r)
+		}
+	}
+	n.validateApplicators(r, ev)
+	switch v.Kind() {
+	case value.ArrayKind:
+		if a, ok := v.(value.Array); ok {
+			n.validateUnevaluatedItems(r, a, ev)
+		}
+	case value.MapKind:
+		if m, ok := v.(value.Map); ok {
+			n.validateUnevaluatedProperties(r, m, ev)
+		}
+	}
+	return r.violations, ev
+}
+
+func (n *node) validateAny(r *report) {
+	if len(n.types) > 0 {
+		matched := false
+		for _, t := range n.types {
+			matched = matched || hasType(r.v, t)
+		}
+		if !matched {
+			r.add("type", "expected %s, but got %s", strings.Join(n.types, " or "), jsonType(r.v))
+		}
+	}
+	if n.enum != nil {
+		matched := false
+		for _, allowed := range n.enum {
+			matched = matched || equal(r.v, allowed)
+		}
+		if !matched {
+			var allowed []string
+			for _, v := range n.enum {
+				allowed = append(allowed, describe(v))
+			}
+			r.add("enum", "%s is not one of %s", describe(r.v), strings.Join(allowed, ", "))
+		}
+	}
+	if n.constant != nil && !equal(r.v, n.constant) {
+		r.add("const", "expected %s, but got %s", describe(n.constant), describe(r.v))
+	}
+}
+
+func describe(v value.Value) string {
+	switch v.Kind() {
+	case value.StringKind, value.TimestampKind, value.DurationKind, value.BytesKind:
+		return fmt.Sprintf("%q", v.(value.Simple).String())
+	case value.ArrayKind, value.MapKind:
+		return jsonType(v)
+	}
+	if s, ok := v.(value.Simple); ok {
+		return s.String()
+	}
+	return v.Kind().String()
+}
+
+func (n *node) validateNumber(r *report) {
+	x, ok := ratOf(r.v)
+	if !ok {
+		return
+	}
+	text := describe(r.v)
+	if n.multipleOf != nil && !new(big.Rat).Quo(x, n.multipleOf.rat).IsInt() {
+		r.add("multipleOf", "%s is not a multiple of %s", text, n.multipleOf.text)
+	}
+	if n.maximum != nil && x.Cmp(n.maximum.rat) > 0 {
+		r.add("maximum", "%s is greater than the maximum %s", text, n.maximum.text)
+	}
+	if n.exclusiveMaximum != nil && x.Cmp(n.exclusiveMaximum.rat) >= 0 {
+		r.add("exclusiveMaximum", "%s is not less than %s", text, n.exclusiveMaximum.text)
+	}
+	if n.minimum != nil && x.Cmp(n.minimum.rat) < 0 {
+		r.add("minimum", "%s is less than the minimum %s", text, n.minimum.text)
+	}
+	if n.exclusiveMinimum != nil && x.Cmp(n.exclusiveMinimum.rat) <= 0 {
+		r.add("exclusiveMinimum", "%s is not greater than %s", text, n.exclusiveMinimum.text)
+	}
+}
+
+func (n *node) validateString(r *report) {
+	s, ok := r.v.(value.Simple)
+	if !ok {
+		return
+	}
+	text := s.String()
+	length := utf8.RuneCountInString(text)
+	if n.maxLength != nil && length > *n.maxLength {
+		r.add("maxLength", "length %d is greater than %d", length, *n.maxLength)
+	}
+	if n.minLength != nil && length < *n.minLength {
+		r.add("minLength", "length %d is less than %d", length, *n.minLength)
+	}
+	if n.pattern != nil && !n.pattern.MatchString(text) {
+		r.add("pattern", "%q does not match %q", text, n.pattern.String())
+	}
+}
+
+func (n *node) validateArray(r *report, a value.Array, ev *evaluated) {
+	items, err := elements(a)
+	if err != nil {
+		r.add("items", "%s", err)
+		return
+	}
+	for i, item := range items {
+		itemPath := childPath(r.p, key.Value[int]{X: i})
+		if i < len(n.prefixItems) {
+			r.child("prefixItems", n.prefixItems[i], item, itemPath, fmt.Sprintf("item %d", i))
+			ev.item(i)
+		} else if n.items != nil {
+			r.child("items", n.items, item, itemPath, fmt.Sprintf("item %d", i))
+		}
+	}
+	if n.items != nil {
+		ev.allItems = true
+	}
+	if n.contains != nil {
+		matches := 0
+		for i, item := range items {
+			if violations, _ := n.contains.validate(item, childPath(r.p, key.Value[int]{X: i})); len(violations) == 0 {
+				matches++
+				ev.item(i)
+			}
+		}
+		minContains := 1
+		if n.minContains != nil {
+			minContains = *n.minContains
+		}
+		if matches < minContains {
+			r.add("contains", "%d items match the contains schema, but at least %d must", matches, minContains)
+		}
+		if n.maxContains != nil && matches > *n.maxContains {
+			r.add("maxContains", "%d items match the contains schema, but at most %d may", matches, *n.maxContains)
+		}
+	}
+	if n.maxItems != nil && len(items) > *n.maxItems {
+		r.add("maxItems", "%d items is more than %d", len(items), *n.maxItems)
+	}
+	if n.minItems != nil && len(items) < *n.minItems {
+		r.add("minItems", "%d items is fewer than %d", len(items), *n.minItems)
+	}
+	if n.uniqueItems {
+		for i := range items {
+			for j := i + 1; j < len(items); j++ {
+				if equal(items[i], items[j]) {
+					r.add("uniqueItems", "items %d and %d are equal", i, j)
+				}
+			}
+		}
+	}
+}
+
+func (n *node) validateObject(r *report, m value.Map, ev *evaluated) {
+	fields, err := sortedFields(m)
+	if err != nil {
+		r.add("properties", "%s", err)
+		return
+	}
+	present := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		present[f.name] = true
+	}
+	for _, name := range n.required {
+		if !present[name] {
+			r.add("required", "missing required property %q", name)
+		}
+	}
+	for _, f := range fields {
+		for _, other := range n.dependentRequired[f.name] {
+			if !present[other] {
+				r.add("dependentRequired", "property %q is required when %q is present", other, f.name)
+			}
+		}
+	}
+	for _, f := range fields {
+		fieldPath := childPath(r.p, key.Value[string]{X: f.name})
+		what := fmt.Sprintf("property %q", f.name)
+		matched := false
+		if sub, ok := n.properties[f.name]; ok {
+			matched = true
+			r.child("properties", sub, f.value, fieldPath, what)
+			ev.property(f.name)
+		}
+		for _, pp := range n.patternProperties {
+			if pp.pattern.MatchString(f.name) {
+				matched = true
+				r.child("patternProperties", pp.node, f.value, fieldPath, what)
+				ev.property(f.name)
+			}
+		}
+		if !matched && n.additionalProperties != nil {
+			r.child("additionalProperties", n.additionalProperties, f.value, fieldPath, what)
+			ev.property(f.name)
+		}
+		if n.propertyNames != nil {
+			r.child("propertyNames", n.propertyNames, value.NewString(f.name, f.value.Source()), fieldPath, fmt.Sprintf("property name %q", f.name))
+		}
+		if sub, ok := n.dependentSchemas[f.name]; ok {
+			violations, subEv := sub.validate(r.v, r.p)
+			r.violations = append(r.violations, violations...)
+			if len(violations) == 0 {
+				ev.merge(subEv)
+			}
+		}
+	}
+	if n.maxProperties != nil && len(fields) > *n.maxProperties {
+		r.add("maxProperties", "%d properties is more than %d", len(fields), *n.maxProperties)
+	}
+	if n.minProperties != nil && len(fields) < *n.minProperties {
+		r.add("minProperties", "%d properties is fewer than %d", len(fields), *n.minProperties)
+	}
+}
+
+func (n *node) validateApplicators(r *report, ev *evaluated) {
+	for _, sub := range n.allOf {
+		violations, subEv := sub.validate(r.v, r.p)
+		r.violations = append(r.violations, violations...)
+		if len(violations) == 0 {
+			ev.merge(subEv)
+		}
+	}
+	if len(n.anyOf) > 0 {
+		matches := 0
+		for _, sub := range n.anyOf {
+			if violations, subEv := sub.validate(r.v, r.p); len(violations) == 0 {
+				matches++
+				ev.merge(subEv)
+			}
+		}
+		if matches == 0 {
+			r.add("anyOf", "does not match any of the %d schemas", len(n.anyOf))
+		}
+	}
+	if len(n.oneOf) > 0 {
+		matches := 0
+		var matchedEv *evaluated
+		for _, sub := range n.oneOf {
+			if violations, subEv := sub.validate(r.v, r.p); len(violations) == 0 {
+				matches++
+				matchedEv = subEv
+			}
+		}
+		if matches == 1 {
+			ev.merge(matchedEv)
+		} else {
+			r.add("oneOf", "matches %d of the %d schemas, but must match exactly one", matches, len(n.oneOf))
+		}
+	}
+	if n.not != nil {
+		if violations, _ := n.not.validate(r.v, r.p); len(violations) == 0 {
+			r.add("not", "must not match the schema")
+		}
+	}
+	if n.ifNode != nil {
+		violations, ifEv := n.ifNode.validate(r.v, r.p)
+		branch, keyword := n.elseNode, "else"
+		if len(violations) == 0 {
+			ev.merge(ifEv)
+			branch, keyword = n.thenNode, "then"
+		}
+		if branch != nil {
+			if branch.boolean != nil && !*branch.boolean {
+				r.add(keyword, "no value is allowed")
+			} else {
+				violations, branchEv := branch.validate(r.v, r.p)
+				r.violations = append(r.violations, violations...)
+				if len(violations) == 0 {
+					ev.merge(branchEv)
+				}
+			}
+		}
+	}
+}
+
+func (n *node) validateUnevaluatedItems(r *report, a value.Array, ev *evaluated) {
+	if n.unevaluatedItems == nil || ev.allItems {
+		return
+	}
+	items, err := elements(a)
+	if err != nil {
+		r.add("unevaluatedItems", "%s", err)
+		return
+	}
+	for i, item := range items {
+		if !ev.items[i] {
+			r.child("unevaluatedItems", n.unevaluatedItems, item, childPath(r.p, key.Value[int]{X: i}), fmt.Sprintf("item %d", i))
+		}
+	}
+	ev.allItems = true
+}
+
+func (n *node) validateUnevaluatedProperties(r *report, m value.Map, ev *evaluated) {
+	if n.unevaluatedProperties == nil {
+		return
+	}
+	fields, err := sortedFields(m)
+	if err != nil {
+		r.add("unevaluatedProperties", "%s", err)
+		return
+	}
+	for _, f := range fields {
+		if !ev.properties[f.name] {
+			r.child("unevaluatedProperties", n.unevaluatedProperties, f.value, childPath(r.p, key.Value[string]{X: f.name}), fmt.Sprintf("property %q", f.name))
+		}
+		ev.property(f.name)
+	}
+}
@@ -180,6 +180,10 @@ func (m *genericMap[K, T]) ForEach(f func(index key.Interface, value T) error) e
 
 type mapImpl struct {
 	genericMap[string, Value]
+	// keys holds the key of each element not keyed by a key.Value[string],
+	// such as the integer keys of a go map[int]T. Elements are stored by
+	// KeyName, so they may also be looked up by name.
+	keys map[string]key.Interface
 }
 
 var _ ModifiableMap = &mapImpl{}
@@ -187,8 +191,23 @@ var _ ModifiableMap = &mapImpl{}
 func (m *mapImpl) Length() (int, error) {
 	return len(m.elements), nil
 }
-func (m *mapImpl) Field(key key.Interface) (Value, error) {
-	return m.genericMap.Field(key)
+
+// name returns the name the element for k is stored under. A key other than
+// a key.Value[string] only matches an element stored with that key.
+func (m *mapImpl) name(k key.Interface) (string, bool) {
+	if s, ok := k.(key.Value[string]); ok {
+		return s.X, true
+	}
+	name := KeyName(k)
+	return name, m.keys[name] == k
+}
+
+func (m *mapImpl) Field(k key.Interface) (Value, error) {
+	name, ok := m.name(k)
+	if !ok {
+		return nil, fmt.Errorf("field not found: %s", k)
+	}
+	return m.genericMap.Field(key.Value[string]{X: name})
 }
 func (m *mapImpl) Source() Source {
 	return m.source
@@ -198,8 +217,20 @@ func (m *mapImpl) Kind() Kind {
 	return MapKind
 }
 
-func (m *mapImpl) SetField(key key.Interface, value Value) error {
-	return m.genericMap.SetField(key, value)
+func (m *mapImpl) SetField(k key.Interface, value Value) error {
+	switch k := k.(type) {
+	case key.Value[string]:
+		// the element is now keyed by name
+		delete(m.keys, k.X)
+	case key.Value[int], key.Value[bool]:
+		if m.keys == nil {
+			m.keys = make(map[string]key.Interface)
+		}
+		m.keys[KeyName(k)] = k
+	default:
+		return fmt.Errorf("unsupported map key %T", k)
+	}
+	return m.genericMap.SetField(key.Value[string]{X: KeyName(k)}, value)
 }
 
 func (m *mapImpl) SetValue(value Value) error {
@@ -208,6 +239,9 @@ func (m *mapImpl) SetValue(value Value) error {
 
 func (m *mapImpl) ForEach(f func(index key.Interface, value Value) error) error {
 	return m.genericMap.ForEach(func(index key.Interface, value Value) error {
+		if k, ok := m.keys[KeyName(index)]; ok {
+			index = k
+		}
 		return f(index, value)
 	})
 }
@@ -225,3 +259,18 @@ func NewMap(elements map[string]Value, source Source) Map {
 		genericMap: genericMap[string, Value]{elements: elements, source: source},
 	}
 }
+
+// NewKeyedMap returns a native map holding values[i] at keys[i]. Keys of
+// type key.Value[int] and key.Value[bool] are kept, so that iterating the map
+// gives the same keys as a reflected view of a go map keyed by ints or bools.
+func NewKeyedMap(keys []key.Interface, values []Value, source Source) (Map, error) {
+	m := &mapImpl{
+		genericMap: genericMap[string, Value]{elements: make(map[string]Value, len(keys)), source: source},
+	}
+	for i, k := range keys {
+		if err := m.SetField(k, values[i]); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,161 @@
+package value
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+)
+
+type CompareFunc func(left, right Value) (int, error)
+
+// Compare imposes a total order over values. Values of different kinds are
+// ordered by kind, simple values by CompareTo, arrays element by element and
+// maps by their sorted keys and then their values. A nil Value sorts first.
+func Compare(left, right Value) (int, error) {
+	if left == nil || right == nil {
+		switch {
+		case left == right:
+			return 0, nil
+		case left == nil:
+			return -1, nil
+		default:
+			return 1, nil
+		}
+	}
+	leftKind, rightKind := left.Kind(), right.Kind()
+	if leftKind != rightKind {
+		if leftKind < rightKind {
+			return -1, nil
+		}
+		return 1, nil
+	}
+	switch leftKind {
+	case NullKind:
+		return 0, nil
+	case ArrayKind:
+		return compareArrays(left, right)
+	case MapKind:
+		return compareMaps(left, right)
+	}
+	l, ok := left.(Simple)
+	if !ok {
+		return 0, fmt.Errorf("cannot compare %s value %T", leftKind, left)
+	}
+	r, ok := right.(Simple)
+	if !ok {
+		return 0, fmt.Errorf("cannot compare %s value %T", rightKind, right)
+	}
+	return l.CompareTo(r)
+}
+
+func collectElements(c Collection) ([]key.Interface, []Value, error) {
+	var keys []key.Interface
+	var values []Value
+	err := c.ForEach(func(k key.Interface, v Value) error {
+		keys = append(keys, k)
+		values = append(values, v)
+		return nil
+	})
+	return keys, values, err
+}
+
+func compareArrays(left, right Value) (int, error) {
+	l, ok := left.(Array)
+	if !ok {
+		return 0, fmt.Errorf("expected array, but got %T", left)
+	}
+	r, ok := right.(Array)
+	if !ok {
+		return 0, fmt.Errorf("expected array, but got %T", right)
+	}
+	_, leftValues, err := collectElements(l)
+	if err != nil {
+		return 0, err
+	}
+	_, rightValues, err := collectElements(r)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(leftValues) && i < len(rightValues); i++ {
+		result, err := Compare(leftValues[i], rightValues[i])
+		if err != nil || result != 0 {
+			return result, err
+		}
+	}
+	return compareInts(len(leftValues), len(rightValues)), nil
+}
+
+type sortedEntries struct {
+	keys   []string
+	values []Value
+}
+
+func sortedMapEntries(m Map) (*sortedEntries, error) {
+	keys, values, err := collectElements(m)
+	if err != nil {
+		return nil, err
+	}
+	entries := &sortedEntries{
+		keys:   make([]string, len(keys)),
+		values: values,
+	}
+	for i, k := range keys {
+		entries.keys[i] = k.String()
+	}
+	sort.Sort(entries)
+	return entries, nil
+}
+
+func (e *sortedEntries) Len() int {
+	return len(e.keys)
+}
+func (e *sortedEntries) Less(i, j int) bool {
+	return e.keys[i] < e.keys[j]
+}
+func (e *sortedEntries) Swap(i, j int) {
+	e.keys[i], e.keys[j] = e.keys[j], e.keys[i]
+	e.values[i], e.values[j] = e.values[j], e.values[i]
+}
+
+func compareMaps(left, right Value) (int, error) {
+	l, ok := left.(Map)
+	if !ok {
+		return 0, fmt.Errorf("expected map, but got %T", left)
+	}
+	r, ok := right.(Map)
+	if !ok {
+		return 0, fmt.Errorf("expected map, but got %T", right)
+	}
+	leftEntries, err := sortedMapEntries(l)
+	if err != nil {
+		return 0, err
+	}
+	rightEntries, err := sortedMapEntries(r)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(leftEntries.keys) && i < len(rightEntries.keys); i++ {
+		if leftEntries.keys[i] != rightEntries.keys[i] {
+			if leftEntries.keys[i] < rightEntries.keys[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+		result, err := Compare(leftEntries.values[i], rightEntries.values[i])
+		if err != nil || result != 0 {
+			return result, err
+		}
+	}
+	return compareInts(len(leftEntries.keys), len(rightEntries.keys)), nil
+}
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
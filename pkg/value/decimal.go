@@ -0,0 +1,196 @@
+package value
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact base 10 number. Unlike floats, 0.1+0.2 is exactly 0.3.
+type Decimal interface {
+	Number
+	Rat() *big.Rat
+	Scale() int
+	Add(other Number) (Decimal, error)
+	Sub(other Number) (Decimal, error)
+	Mul(other Number) (Decimal, error)
+	Quo(other Number, scale int) (Decimal, error)
+}
+
+type decimalImpl struct {
+	value  *big.Rat
+	scale  int
+	source Source
+}
+
+var _ Decimal = (&decimalImpl{})
+var _ ClassifiedNumber = (&decimalImpl{})
+
+// decimalScale returns the number of fraction digits needed to write s
+// without loss, or an error if s is not a finite decimal.
+func decimalScale(s string) (int, error) {
+	mantissa, exponent := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		mantissa = s[:i]
+		exponent, err = strconv.Atoi(s[i+1:])
+		if err != nil {
+			return 0, fmt.Errorf("invalid decimal: %s", s)
+		}
+	}
+	scale := 0
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		scale = len(mantissa) - i - 1
+	}
+	scale -= exponent
+	if scale < 0 {
+		scale = 0
+	}
+	return scale, nil
+}
+
+func ParseDecimal(value string, source Source) (Decimal, error) {
+	if strings.ContainsAny(value, "/xXpP_") {
+		return nil, fmt.Errorf("invalid decimal: %s", value)
+	}
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal: %s", value)
+	}
+	scale, err := decimalScale(value)
+	if err != nil {
+		return nil, err
+	}
+	return &decimalImpl{r, scale, source}, nil
+}
+
+func NewDecimal(value *big.Rat, scale int, source Source) Decimal {
+	return &decimalImpl{roundRat(value, scale), scale, source}
+}
+
+// roundRat rounds r to scale fraction digits, with ties going to the even
+// digit.
+func roundRat(r *big.Rat, scale int) *big.Rat {
+	if scale < 0 {
+		scale = 0
+	}
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	num := new(big.Int).Mul(r.Num(), factor)
+	quo, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+		c := twice.Cmp(r.Denom())
+		if c > 0 || (c == 0 && quo.Bit(0) == 1) {
+			if num.Sign() < 0 {
+				quo.Sub(quo, big.NewInt(1))
+			} else {
+				quo.Add(quo, big.NewInt(1))
+			}
+		}
+	}
+	return new(big.Rat).SetFrac(quo, factor)
+}
+
+func numberToDecimal(n Number) (*decimalImpl, error) {
+	if d, ok := n.(*decimalImpl); ok {
+		return d, nil
+	}
+	if NumberTypeOf(n) == ComplexNumber {
+		return nil, fmt.Errorf("cannot convert complex number %s to decimal", n.String())
+	}
+	d, err := ParseDecimal(n.String(), n.Source())
+	if err != nil {
+		return nil, err
+	}
+	return d.(*decimalImpl), nil
+}
+
+func (d *decimalImpl) Rat() *big.Rat {
+	return new(big.Rat).Set(d.value)
+}
+func (d *decimalImpl) Scale() int {
+	return d.scale
+}
+func (d *decimalImpl) NumberType() NumberType {
+	return DecimalNumber
+}
+func (d *decimalImpl) Int(bits int) (int64, error) {
+	if !d.value.IsInt() {
+		return 0, fmt.Errorf("%s is not an integer", d.String())
+	}
+	return strconv.ParseInt(d.value.Num().String(), 10, bits)
+}
+func (d *decimalImpl) Unsigned(bits int) (uint64, error) {
+	if !d.value.IsInt() {
+		return 0, fmt.Errorf("%s is not an integer", d.String())
+	}
+	return strconv.ParseUint(d.value.Num().String(), 10, bits)
+}
+func (d *decimalImpl) BigInt() (*big.Int, error) {
+	if !d.value.IsInt() {
+		return nil, fmt.Errorf("%s is not an integer", d.String())
+	}
+	return new(big.Int).Set(d.value.Num()), nil
+}
+func (d *decimalImpl) Float(bits int) (float64, error) {
+	return strconv.ParseFloat(d.String(), bits)
+}
+func (d *decimalImpl) Complex(bits int) (complex128, error) {
+	f, err := d.Float(bits / 2)
+	return complex(f, 0), err
+}
+func (d *decimalImpl) Source() Source {
+	return d.source
+}
+func (d *decimalImpl) Kind() Kind {
+	return NumberKind
+}
+func (d *decimalImpl) String() string {
+	return d.value.FloatString(d.scale)
+}
+func (d *decimalImpl) WithoutSource() interface{} {
+	return d.String()
+}
+func (d *decimalImpl) CompareTo(other Simple) (int, error) {
+	if other, ok := other.(Number); ok && other.Kind() == NumberKind {
+		return CompareNumbers(d, other)
+	}
+	return 0, fmt.Errorf("cannot compare decimal to %T", other)
+}
+
+func (d *decimalImpl) Add(other Number) (Decimal, error) {
+	o, err := numberToDecimal(other)
+	if err != nil {
+		return nil, err
+	}
+	return &decimalImpl{new(big.Rat).Add(d.value, o.value), max(d.scale, o.scale), d.source}, nil
+}
+
+func (d *decimalImpl) Sub(other Number) (Decimal, error) {
+	o, err := numberToDecimal(other)
+	if err != nil {
+		return nil, err
+	}
+	return &decimalImpl{new(big.Rat).Sub(d.value, o.value), max(d.scale, o.scale), d.source}, nil
+}
+
+func (d *decimalImpl) Mul(other Number) (Decimal, error) {
+	o, err := numberToDecimal(other)
+	if err != nil {
+		return nil, err
+	}
+	return &decimalImpl{new(big.Rat).Mul(d.value, o.value), d.scale + o.scale, d.source}, nil
+}
+
+// Quo divides by other, rounding the result to scale fraction digits.
+func (d *decimalImpl) Quo(other Number, scale int) (Decimal, error) {
+	o, err := numberToDecimal(other)
+	if err != nil {
+		return nil, err
+	}
+	if o.value.Sign() == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return NewDecimal(new(big.Rat).Quo(d.value, o.value), scale, d.source), nil
+}
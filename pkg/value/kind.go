@@ -34,6 +34,9 @@ const (
 	StringKind
 	BoolKind
 	NumberKind
+	TimestampKind
+	DurationKind
+	BytesKind
 )
 const (
 	ArrayKind = Kind(iota + CollectionClass)
@@ -52,6 +55,12 @@ func (kind Kind) String() string {
 		return "Bool"
 	case NumberKind:
 		return "Number"
+	case TimestampKind:
+		return "Timestamp"
+	case DurationKind:
+		return "Duration"
+	case BytesKind:
+		return "Bytes"
 	case ArrayKind:
 		return "Array"
 	case MapKind:
@@ -0,0 +1,96 @@
+package value
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// NumberTypeOf returns the type of n, classifying its text if n is not a
+// ClassifiedNumber.
+func NumberTypeOf(n Number) NumberType {
+	if c, ok := n.(ClassifiedNumber); ok {
+		return c.NumberType()
+	}
+	numberType, err := classifyNumber(n.String())
+	if err != nil {
+		return UnknownNumber
+	}
+	return numberType
+}
+
+// BigIntOf returns n as a big.Int, or an error if it is not an integer.
+func BigIntOf(n Number) (*big.Int, error) {
+	if c, ok := n.(ClassifiedNumber); ok {
+		return c.BigInt()
+	}
+	i, ok := new(big.Int).SetString(n.String(), 10)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an integer", n.String())
+	}
+	return i, nil
+}
+
+// CompareNumbers compares two numbers without loss of precision. Integers
+// and decimal floats are compared as exact rationals; complex numbers are
+// ordered by their real and then their imaginary parts.
+func CompareNumbers(left, right Number) (int, error) {
+	leftType, rightType := NumberTypeOf(left), NumberTypeOf(right)
+	if leftType == ComplexNumber || rightType == ComplexNumber {
+		return compareComplex(left, right)
+	}
+	if leftType.IsInteger() && rightType.IsInteger() {
+		l, err := BigIntOf(left)
+		if err != nil {
+			return 0, err
+		}
+		r, err := BigIntOf(right)
+		if err != nil {
+			return 0, err
+		}
+		return l.Cmp(r), nil
+	}
+	l, lOk := new(big.Rat).SetString(left.String())
+	r, rOk := new(big.Rat).SetString(right.String())
+	if lOk && rOk {
+		return l.Cmp(r), nil
+	}
+	// Inf and NaN have no rational representation
+	lf, err := left.Float(64)
+	if err != nil {
+		return 0, err
+	}
+	rf, err := right.Float(64)
+	if err != nil {
+		return 0, err
+	}
+	return compareFloats(lf, rf)
+}
+
+func compareFloats(l, r float64) (int, error) {
+	switch {
+	case l < r:
+		return -1, nil
+	case l > r:
+		return 1, nil
+	case l == r:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("cannot compare %v to %v", l, r)
+	}
+}
+
+func compareComplex(left, right Number) (int, error) {
+	l, err := left.Complex(128)
+	if err != nil {
+		return 0, err
+	}
+	r, err := right.Complex(128)
+	if err != nil {
+		return 0, err
+	}
+	result, err := compareFloats(real(l), real(r))
+	if err != nil || result != 0 {
+		return result, err
+	}
+	return compareFloats(imag(l), imag(r))
+}
@@ -0,0 +1,227 @@
+package value
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+)
+
+type OriginAction string
+
+const (
+	NoAction      OriginAction = ""
+	OverriddenBy  OriginAction = "overridden by"
+	DefaultedFrom OriginAction = "defaulted from"
+	MergedWith    OriginAction = "merged with"
+	Via           OriginAction = "via"
+	IncludedFrom  OriginAction = "included from"
+	DerivedFrom   OriginAction = "derived from"
+)
+
+type Origin struct {
+	Action OriginAction
+	Source Source
+}
+
+// Provenance is a Source recording every place a value came from, oldest
+// first, e.g. "defaults.yaml [Ln=12,Col=3] overridden by prod.yaml [Ln=4,Col=3]".
+type Provenance struct {
+	origins []Origin
+}
+
+// ProvenanceOf returns source as a Provenance, wrapping it if necessary.
+func ProvenanceOf(source Source) *Provenance {
+	if p, ok := source.(*Provenance); ok {
+		return p
+	}
+	if source == nil {
+		source = UnknownSource
+	}
+	return &Provenance{origins: []Origin{{Source: source}}}
+}
+
+// Then returns a new Provenance extending p with source, reached by action.
+// p is not modified.
+func (p *Provenance) Then(action OriginAction, source Source) *Provenance {
+	next := ProvenanceOf(source)
+	origins := make([]Origin, 0, len(p.origins)+len(next.origins))
+	origins = append(origins, p.origins...)
+	for i, origin := range next.origins {
+		if i == 0 {
+			origin.Action = action
+		}
+		origins = append(origins, origin)
+	}
+	return &Provenance{origins: origins}
+}
+
+func (p *Provenance) Origins() []Origin {
+	return append([]Origin(nil), p.origins...)
+}
+
+// Current returns the most recent origin's source.
+func (p *Provenance) Current() Source {
+	return p.origins[len(p.origins)-1].Source
+}
+
+func (p *Provenance) String() string {
+	sb := strings.Builder{}
+	for i, origin := range p.origins {
+		if i > 0 {
+			sb.WriteString(" ")
+			if origin.Action != NoAction {
+				sb.WriteString(string(origin.Action))
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(origin.Source.String())
+	}
+	return sb.String()
+}
+
+// WithSource returns a shallow copy of v with a different source. Children of
+// collections are shared with v. Values not created by this package are
+// converted to their native equivalent.
+func WithSource(v Value, source Source) (Value, error) {
+	switch v := v.(type) {
+	case *stringImpl:
+		return &stringImpl{v.value, source}, nil
+	case *taggedStringImpl:
+		return NewTaggedString(v.value, v.tag, source), nil
+	case *boolImpl:
+		return &boolImpl{v.value, source}, nil
+	case *numberImpl:
+		return &numberImpl{v.value, v.numberType, source}, nil
+	case *decimalImpl:
+		return &decimalImpl{v.value, v.scale, source}, nil
+	case *timestampImpl:
+		return &timestampImpl{v.value, source}, nil
+	case *durationImpl:
+		return &durationImpl{v.value, source}, nil
+	case *bytesImpl:
+		return &bytesImpl{v.value, source}, nil
+	case *Null:
+		return &Null{source}, nil
+	case *arrayImpl:
+		elements := append([]Value(nil), v.elements...)
+		return NewArray(elements, source), nil
+	case *mapImpl:
+		elements := make(map[string]Value, len(v.elements))
+		for k, child := range v.elements {
+			elements[k] = child
+		}
+		var keys map[string]key.Interface
+		if v.keys != nil {
+			keys = make(map[string]key.Interface, len(v.keys))
+			for name, k := range v.keys {
+				keys[name] = k
+			}
+		}
+		return &mapImpl{genericMap[string, Value]{elements: elements, source: source}, keys}, nil
+	}
+	return withSourceByKind(v, source)
+}
+
+// withSourceByKind copies values of types defined outside this package using
+// the interface for their kind.
+func withSourceByKind(v Value, source Source) (Value, error) {
+	var err error
+	switch v.Kind() {
+	case NullKind:
+		return NewNull(source), nil
+	case StringKind:
+		if s, ok := v.(String); ok {
+			var text string
+			text, err = s.StringOrError()
+			if err == nil {
+				return NewString(text, source), nil
+			}
+		}
+	case BoolKind:
+		if b, ok := v.(Bool); ok {
+			var value bool
+			value, err = b.Bool()
+			if err == nil {
+				return NewBool(value, source), nil
+			}
+		}
+	case NumberKind:
+		if d, ok := v.(Decimal); ok {
+			return NewDecimal(d.Rat(), d.Scale(), source), nil
+		}
+		if n, ok := v.(Number); ok {
+			var numberType NumberType
+			numberType, err = classifyNumber(n.String())
+			if err == nil {
+				return &numberImpl{n.String(), numberType, source}, nil
+			}
+		}
+	case TimestampKind:
+		if t, ok := v.(Timestamp); ok {
+			var value time.Time
+			value, err = t.Time()
+			if err == nil {
+				return NewTimestamp(value, source), nil
+			}
+		}
+	case DurationKind:
+		if d, ok := v.(Duration); ok {
+			var value time.Duration
+			value, err = d.Duration()
+			if err == nil {
+				return NewDuration(value, source), nil
+			}
+		}
+	case BytesKind:
+		if b, ok := v.(Bytes); ok {
+			var value []byte
+			value, err = b.Bytes()
+			if err == nil {
+				return NewBytes(value, source), nil
+			}
+		}
+	case ArrayKind:
+		if a, ok := v.(Array); ok {
+			var elements []Value
+			_, elements, err = collectElements(a)
+			if err == nil {
+				return NewArray(elements, source), nil
+			}
+		}
+	case MapKind:
+		if m, ok := v.(Map); ok {
+			var keys []key.Interface
+			var values []Value
+			keys, values, err = collectElements(m)
+			if err == nil {
+				return NewKeyedMap(keys, values, source)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("cannot change the source of %T", v)
+}
+
+// KeyName returns the name used for k in a native map.
+func KeyName(k key.Interface) string {
+	switch k := k.(type) {
+	case key.Value[string]:
+		return k.X
+	case key.Value[int]:
+		return strconv.Itoa(k.X)
+	case key.Value[bool]:
+		return strconv.FormatBool(k.X)
+	}
+	return k.String()
+}
+
+// Derive returns a shallow copy of v whose source records that it was reached
+// from source by action, after wherever v itself came from.
+func Derive(v Value, action OriginAction, source Source) (Value, error) {
+	return WithSource(v, ProvenanceOf(v.Source()).Then(action, source))
+}
@@ -1,5 +1,10 @@
 package value
 
+import (
+	"math/big"
+	"time"
+)
+
 type Simple interface {
 	Value
 	String() string
@@ -16,6 +21,38 @@ type Bool interface {
 	Bool() (bool, error)
 }
 
+type NumberType int
+
+const (
+	UnknownNumber NumberType = iota
+	IntegerNumber
+	FloatNumber
+	BigIntegerNumber
+	ComplexNumber
+	DecimalNumber
+)
+
+func (t NumberType) String() string {
+	switch t {
+	case IntegerNumber:
+		return "Integer"
+	case FloatNumber:
+		return "Float"
+	case BigIntegerNumber:
+		return "BigInteger"
+	case ComplexNumber:
+		return "Complex"
+	case DecimalNumber:
+		return "Decimal"
+	default:
+		return "Unknown"
+	}
+}
+
+func (t NumberType) IsInteger() bool {
+	return t == IntegerNumber || t == BigIntegerNumber
+}
+
 type Number interface {
 	Simple
 	Int(bits int) (int64, error)
@@ -23,3 +60,26 @@ type Number interface {
 	Unsigned(bits int) (uint64, error)
 	Complex(bits int) (complex128, error)
 }
+
+// ClassifiedNumber is implemented by numbers which know their NumberType, such
+// as those of this package. NumberTypeOf and BigIntOf accept any Number.
+type ClassifiedNumber interface {
+	Number
+	NumberType() NumberType
+	BigInt() (*big.Int, error)
+}
+
+type Timestamp interface {
+	Simple
+	Time() (time.Time, error)
+}
+
+type Duration interface {
+	Simple
+	Duration() (time.Duration, error)
+}
+
+type Bytes interface {
+	Simple
+	Bytes() ([]byte, error)
+}
@@ -1,9 +1,13 @@
 package value
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
+	"time"
 	"unsafe"
 
 	"golang.org/x/exp/constraints"
@@ -36,7 +40,7 @@ func (s *stringImpl) WithoutSource() interface{} {
 	return s.value
 }
 func (s *stringImpl) CompareTo(other Simple) (int, error) {
-	if other, ok := other.(String); ok {
+	if other, ok := other.(String); ok && other.Kind() == StringKind {
 		return strings.Compare(s.value, other.String()), nil
 	}
 	return 0, fmt.Errorf("cannot compare string to %T", other)
@@ -67,7 +71,7 @@ func (b *boolImpl) WithoutSource() interface{} {
 	return b.value
 }
 func (b *boolImpl) CompareTo(other Simple) (int, error) {
-	if other, ok := other.(Bool); ok {
+	if other, ok := other.(Bool); ok && other.Kind() == BoolKind {
 		otherB, err := other.Bool()
 		if err != nil {
 			return 0, err
@@ -89,7 +93,13 @@ func NewBool(value bool, source Source) Bool {
 
 //-------------------------------------------
 
-type numberImpl genericSimple[string]
+type numberImpl struct {
+	value      string
+	numberType NumberType
+	source     Source
+}
+
+var _ ClassifiedNumber = (&numberImpl{})
 
 func (n *numberImpl) Int(bits int) (int64, error) {
 	return strconv.ParseInt(n.value, 10, bits)
@@ -103,6 +113,16 @@ func (n *numberImpl) Unsigned(bits int) (uint64, error) {
 func (n *numberImpl) Complex(bits int) (complex128, error) {
 	return strconv.ParseComplex(n.value, bits)
 }
+func (n *numberImpl) BigInt() (*big.Int, error) {
+	i, ok := new(big.Int).SetString(n.value, 10)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an integer", n.value)
+	}
+	return i, nil
+}
+func (n *numberImpl) NumberType() NumberType {
+	return n.numberType
+}
 func (n *numberImpl) Source() Source {
 	return n.source
 }
@@ -117,54 +137,171 @@ func (n *numberImpl) WithoutSource() interface{} {
 }
 
 func (n *numberImpl) CompareTo(other Simple) (int, error) {
-	if other, ok := other.(Number); ok {
-		otherF, err := other.Float(64)
-		if err != nil {
-			return 0, err
-		}
-		f, err := n.Float(64)
-		if err != nil {
-			return 0, err
-		}
-		if f == otherF {
-			return 0, nil
-		}
-		if f < otherF {
-			return -1, nil
-		}
-		return 1, nil
+	if other, ok := other.(Number); ok && other.Kind() == NumberKind {
+		return CompareNumbers(n, other)
 	}
 	return 0, fmt.Errorf("cannot compare number to %T", other)
 }
 
+func classifyNumber(value string) (NumberType, error) {
+	if i, ok := new(big.Int).SetString(value, 10); ok {
+		if i.IsInt64() || i.IsUint64() {
+			return IntegerNumber, nil
+		}
+		return BigIntegerNumber, nil
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return FloatNumber, nil
+	}
+	if _, err := strconv.ParseComplex(value, 128); err == nil {
+		return ComplexNumber, nil
+	}
+	return UnknownNumber, fmt.Errorf("invalid number: %s", value)
+}
+
 func NewNumber(value string, source Source) Number {
-	_, err := strconv.ParseFloat(value, 64) //todo handle complex
+	numberType, err := classifyNumber(value)
 	if err != nil {
-		panic(fmt.Errorf("invalid number: %s", value))
+		panic(err)
 	}
-	n := &numberImpl{value, source}
-	return n
+	return &numberImpl{value, numberType, source}
 }
 
 func NewInt[T constraints.Integer](value T, source Source) Number {
-	n := &numberImpl{strconv.FormatInt(int64(value), 10), source}
+	n := &numberImpl{strconv.FormatInt(int64(value), 10), IntegerNumber, source}
 	return n
 }
 
 func NewUnsigned[T constraints.Unsigned](value T, source Source) Number {
-	n := &numberImpl{strconv.FormatUint(uint64(value), 10), source}
+	n := &numberImpl{strconv.FormatUint(uint64(value), 10), IntegerNumber, source}
 	return n
 }
 
+func NewBigInt(value *big.Int, source Source) Number {
+	numberType := BigIntegerNumber
+	if value.IsInt64() || value.IsUint64() {
+		numberType = IntegerNumber
+	}
+	return &numberImpl{value.String(), numberType, source}
+}
+
 func NewFloat[T constraints.Float](value T, source Source) Number {
 	bits := int(unsafe.Sizeof(value) * 8)
-	n := &numberImpl{strconv.FormatFloat(float64(value), 'f', -1, bits), source}
+	n := &numberImpl{strconv.FormatFloat(float64(value), 'f', -1, bits), FloatNumber, source}
 	return n
 }
 
 func NewComplex[T constraints.Complex](value T, source Source) Number {
 	bits := int(unsafe.Sizeof(value) * 8)
-	return &numberImpl{strconv.FormatComplex(complex128(value), 'f', -1, bits), source}
+	return &numberImpl{strconv.FormatComplex(complex128(value), 'f', -1, bits), ComplexNumber, source}
+}
+
+//-------------------------------------------
+
+type timestampImpl genericSimple[time.Time]
+
+var _ Timestamp = (&timestampImpl{})
+
+func (t *timestampImpl) Kind() Kind {
+	return TimestampKind
+}
+func (t *timestampImpl) Source() Source {
+	return t.source
+}
+func (t *timestampImpl) String() string {
+	return t.value.Format(time.RFC3339Nano)
+}
+func (t *timestampImpl) Time() (time.Time, error) {
+	return t.value, nil
+}
+func (t *timestampImpl) WithoutSource() interface{} {
+	return t.value
+}
+func (t *timestampImpl) CompareTo(other Simple) (int, error) {
+	if other, ok := other.(Timestamp); ok && other.Kind() == TimestampKind {
+		otherT, err := other.Time()
+		if err != nil {
+			return 0, err
+		}
+		return t.value.Compare(otherT), nil
+	}
+	return 0, fmt.Errorf("cannot compare timestamp to %T", other)
+}
+
+func NewTimestamp(value time.Time, source Source) Timestamp {
+	return &timestampImpl{value, source}
+}
+
+//-------------------------------------------
+
+type durationImpl genericSimple[time.Duration]
+
+var _ Duration = (&durationImpl{})
+
+func (d *durationImpl) Kind() Kind {
+	return DurationKind
+}
+func (d *durationImpl) Source() Source {
+	return d.source
+}
+func (d *durationImpl) String() string {
+	return d.value.String()
+}
+func (d *durationImpl) Duration() (time.Duration, error) {
+	return d.value, nil
+}
+func (d *durationImpl) WithoutSource() interface{} {
+	return d.value
+}
+func (d *durationImpl) CompareTo(other Simple) (int, error) {
+	if other, ok := other.(Duration); ok && other.Kind() == DurationKind {
+		otherD, err := other.Duration()
+		if err != nil {
+			return 0, err
+		}
+		return compareInts(int(d.value), int(otherD)), nil
+	}
+	return 0, fmt.Errorf("cannot compare duration to %T", other)
+}
+
+func NewDuration(value time.Duration, source Source) Duration {
+	return &durationImpl{value, source}
+}
+
+//-------------------------------------------
+
+type bytesImpl genericSimple[[]byte]
+
+var _ Bytes = (&bytesImpl{})
+
+func (b *bytesImpl) Kind() Kind {
+	return BytesKind
+}
+func (b *bytesImpl) Source() Source {
+	return b.source
+}
+func (b *bytesImpl) String() string {
+	return base64.StdEncoding.EncodeToString(b.value)
+}
+func (b *bytesImpl) Bytes() ([]byte, error) {
+	return b.value, nil
+}
+func (b *bytesImpl) WithoutSource() interface{} {
+	return b.value
+}
+func (b *bytesImpl) CompareTo(other Simple) (int, error) {
+	if other, ok := other.(Bytes); ok && other.Kind() == BytesKind {
+		otherB, err := other.Bytes()
+		if err != nil {
+			return 0, err
+		}
+		return bytes.Compare(b.value, otherB), nil
+	}
+	return 0, fmt.Errorf("cannot compare bytes to %T", other)
+}
+
+func NewBytes(value []byte, source Source) Bytes {
+	return &bytesImpl{value, source}
 }
 
 //-------------------------------------------
@@ -173,6 +310,8 @@ type Null struct {
 	source Source
 }
 
+var _ Simple = (&Null{})
+
 func (n *Null) Kind() Kind {
 	return NullKind
 }
@@ -185,6 +324,17 @@ func (n *Null) WithoutSource() interface{} {
 	return nil
 }
 
+func (n *Null) String() string {
+	return "null"
+}
+
+func (n *Null) CompareTo(other Simple) (int, error) {
+	if other != nil && other.Kind() == NullKind {
+		return 0, nil
+	}
+	return 0, fmt.Errorf("cannot compare null to %T", other)
+}
+
 func NewNull(source Source) *Null {
 	n := &Null{source}
 	return n
@@ -0,0 +1,174 @@
+package value
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+)
+
+// SortOption configures Sort and NewSortedView.
+type SortOption func(*sortConfig) error
+
+type sortConfig struct {
+	compare    CompareFunc
+	descending bool
+}
+
+// Descending sorts the largest elements first. Equal elements keep their
+// order.
+func Descending() SortOption {
+	return func(c *sortConfig) error {
+		c.descending = true
+		return nil
+	}
+}
+
+// WithComparator orders elements with compare instead of Compare.
+func WithComparator(compare CompareFunc) SortOption {
+	return func(c *sortConfig) error {
+		if compare == nil {
+			return fmt.Errorf("comparator must not be nil")
+		}
+		c.compare = compare
+		return nil
+	}
+}
+
+// WrapComparator replaces the comparator chosen by the preceding options with
+// wrap(comparator), so that a helper can compare part of each element with
+// the caller's comparator.
+func WrapComparator(wrap func(compare CompareFunc) CompareFunc) SortOption {
+	return func(c *sortConfig) error {
+		compare := wrap(c.compare)
+		if compare == nil {
+			return fmt.Errorf("comparator must not be nil")
+		}
+		c.compare = compare
+		return nil
+	}
+}
+
+// sortedOrder returns the indices of the elements of a in stable sorted order.
+func sortedOrder(a Array, options ...SortOption) ([]int, []Value, error) {
+	config := sortConfig{compare: Compare}
+	for _, option := range options {
+		if err := option(&config); err != nil {
+			return nil, nil, err
+		}
+	}
+	_, elements, err := collectElements(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	order := make([]int, len(elements))
+	for i := range order {
+		order[i] = i
+	}
+	var sortErr error
+	sort.SliceStable(order, func(i, j int) bool {
+		if sortErr != nil {
+			return false
+		}
+		result, err := config.compare(elements[order[i]], elements[order[j]])
+		if err != nil {
+			sortErr = err
+			return false
+		}
+		if config.descending {
+			return result > 0
+		}
+		return result < 0
+	})
+	if sortErr != nil {
+		return nil, nil, sortErr
+	}
+	return order, elements, nil
+}
+
+// Sort reorders the elements of a in place. The sort is stable.
+func Sort(a ModifiableArray, options ...SortOption) error {
+	order, elements, err := sortedOrder(a, options...)
+	if err != nil {
+		return err
+	}
+	if impl, ok := a.(*arrayImpl); ok {
+		for i, n := range order {
+			impl.elements[i] = elements[n]
+		}
+		return nil
+	}
+	index := key.Value[int]{}
+	for index.X = range order {
+		if err := a.SetIndex(index, elements[order[index.X]]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-------------------------------------------
+
+type sortedArray struct {
+	array    Array
+	elements []Value
+}
+
+var _ Array = &sortedArray{}
+
+// NewSortedView returns a read only view of a with its elements in sorted
+// order. The view is a snapshot; later changes to a are not reflected.
+func NewSortedView(a Array, options ...SortOption) (Array, error) {
+	order, elements, err := sortedOrder(a, options...)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]Value, len(order))
+	for i, n := range order {
+		sorted[i] = elements[n]
+	}
+	return &sortedArray{array: a, elements: sorted}, nil
+}
+
+func (s *sortedArray) Kind() Kind {
+	return ArrayKind
+}
+
+func (s *sortedArray) Source() Source {
+	return s.array.Source()
+}
+
+func (s *sortedArray) WithoutSource() interface{} {
+	copy := make([]interface{}, len(s.elements))
+	for i, v := range s.elements {
+		copy[i] = v.WithoutSource()
+	}
+	return copy
+}
+
+func (s *sortedArray) Length() (int, error) {
+	return len(s.elements), nil
+}
+
+func (s *sortedArray) Index(index key.Interface) (Value, error) {
+	iKey, ok := index.(key.Value[int])
+	if !ok {
+		return nil, fmt.Errorf("expected key.Value[int], but got %T", index)
+	}
+	fixedIndex, err := NormalizeIndex(iKey.X, len(s.elements))
+	if err != nil {
+		return nil, err
+	}
+	return s.elements[fixedIndex], nil
+}
+
+func (s *sortedArray) ForEach(f func(index key.Interface, value Value) error) error {
+	index := key.Value[int]{}
+	for i, v := range s.elements {
+		index.X = i
+		if err := f(index, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package value
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SourceFile holds the content of a decoded file so that byte offsets can be
+// converted to lines and columns and excerpts can be shown.
+type SourceFile struct {
+	name       string
+	content    []byte
+	lineStarts []int
+}
+
+func NewSourceFile(name string, content []byte) *SourceFile {
+	f := &SourceFile{name: name, content: content, lineStarts: []int{0}}
+	for i, b := range content {
+		if b == '\n' {
+			f.lineStarts = append(f.lineStarts, i+1)
+		}
+	}
+	return f
+}
+
+func (f *SourceFile) String() string {
+	return f.name
+}
+
+func (f *SourceFile) Name() string {
+	return f.name
+}
+
+func (f *SourceFile) Content() []byte {
+	return f.content
+}
+
+// Position converts a byte offset to a line and column, both counted from 1.
+// Columns count runes rather than bytes.
+func (f *SourceFile) Position(offset int) Position {
+	offset = max(0, min(offset, len(f.content)))
+	line := sort.SearchInts(f.lineStarts, offset+1) - 1
+	start := f.lineStarts[line]
+	return Position{Line: line + 1, Column: utf8.RuneCount(f.content[start:offset]) + 1}
+}
+
+// Offset converts a line and column, as returned by Position, to a byte
+// offset.
+func (f *SourceFile) Offset(p Position) int {
+	if p.Line < 1 {
+		return 0
+	}
+	if p.Line > len(f.lineStarts) {
+		return len(f.content)
+	}
+	offset := f.lineStarts[p.Line-1]
+	for column := 1; column < p.Column && offset < len(f.content) && f.content[offset] != '\n'; column++ {
+		_, size := utf8.DecodeRune(f.content[offset:])
+		offset += size
+	}
+	return offset
+}
+
+func (f *SourceFile) line(n int) string {
+	start := f.lineStarts[n-1]
+	end := len(f.content)
+	if n < len(f.lineStarts) {
+		end = f.lineStarts[n] - 1
+	}
+	return strings.TrimSuffix(string(f.content[start:end]), "\r")
+}
+
+// Span returns the source for the bytes from start up to end.
+func (f *SourceFile) Span(start, end int) *Span {
+	if end < start {
+		end = start
+	}
+	return &Span{
+		File:  f,
+		Start: start,
+		End:   end,
+		From:  f.Position(start),
+		To:    f.Position(end),
+	}
+}
+
+//-------------------------------------------
+
+// Span is the source of a value decoded from a file. Start and End are byte
+// offsets, From and To the corresponding lines and columns. End and To are
+// exclusive.
+type Span struct {
+	File       *SourceFile
+	Start, End int
+	From, To   Position
+}
+
+func (s *Span) String() string {
+	return fmt.Sprintf("%s [Ln=%d,Col=%d-Ln=%d,Col=%d]", s.File.String(), s.From.Line, s.From.Column, s.To.Line, s.To.Column)
+}
+
+func (s *Span) Position() Position {
+	return s.From
+}
+
+const maxExcerptLines = 5
+
+// WrappedSource is implemented by sources which annotate another source.
+type WrappedSource interface {
+	Source
+	Unwrap() Source
+}
+
+// RenderExcerpt writes message, prefixed by its source. If source is a Span
+// the lines it covers are shown with the span underlined, in the style of a
+// compiler error. For a Provenance the most recent origin is shown, and a
+// WrappedSource is unwrapped.
+func RenderExcerpt(w io.Writer, source Source, message string) error {
+	for {
+		if p, ok := source.(*Provenance); ok {
+			source = p.Current()
+		} else if wrapped, ok := source.(WrappedSource); ok && wrapped.Unwrap() != nil {
+			source = wrapped.Unwrap()
+		} else {
+			break
+		}
+	}
+	span, ok := source.(*Span)
+	if !ok || span.File == nil {
+		if source == nil {
+			source = UnknownSource
+		}
+		_, err := fmt.Fprintf(w, "%s: %s\n", source.String(), message)
+		return err
+	}
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "%s:%d:%d: %s\n", span.File.String(), span.From.Line, span.From.Column, message)
+
+	lastLine := span.To.Line
+	if span.To.Column == 1 && lastLine > span.From.Line {
+		// the span ends with a newline
+		lastLine--
+	}
+	if lastLine > len(span.File.lineStarts) {
+		lastLine = len(span.File.lineStarts)
+	}
+	width := len(fmt.Sprint(lastLine))
+	for n := span.From.Line; n <= lastLine; n++ {
+		if n-span.From.Line == maxExcerptLines {
+			fmt.Fprintf(&sb, "%*s | ...\n", width, "")
+			break
+		}
+		text := span.File.line(n)
+		runes := []rune(text)
+		from, to := 1, len(runes)+1
+		if n == span.From.Line {
+			from = span.From.Column
+		}
+		if n == span.To.Line {
+			to = span.To.Column
+		}
+		if to <= from {
+			to = from + 1
+		}
+		fmt.Fprintf(&sb, "%*d | %s\n", width, n, text)
+		underline := strings.Builder{}
+		for i := 0; i < from-1 && i < len(runes); i++ {
+			if runes[i] == '\t' {
+				underline.WriteRune('\t')
+			} else {
+				underline.WriteRune(' ')
+			}
+		}
+		underline.WriteString(strings.Repeat("^", to-from))
+		fmt.Fprintf(&sb, "%*s | %s\n", width, "", underline.String())
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
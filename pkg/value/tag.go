@@ -0,0 +1,24 @@
+package value
+
+// Tagged is implemented by values decoded with an explicit tag, such as YAML's
+// !include.
+type Tagged interface {
+	Value
+	Tag() string
+}
+
+type taggedStringImpl struct {
+	stringImpl
+	tag string
+}
+
+var _ String = (&taggedStringImpl{})
+var _ Tagged = (&taggedStringImpl{})
+
+func (s *taggedStringImpl) Tag() string {
+	return s.tag
+}
+
+func NewTaggedString(value, tag string, source Source) String {
+	return &taggedStringImpl{stringImpl{value, source}, tag}
+}
@@ -0,0 +1,230 @@
+package value
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidjspooner/dsvalue/pkg/key"
+)
+
+func TestNumberTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected NumberType
+	}{
+		{input: "42", expected: IntegerNumber},
+		{input: "-42", expected: IntegerNumber},
+		{input: "18446744073709551615", expected: IntegerNumber},
+		{input: "123456789012345678901234567890", expected: BigIntegerNumber},
+		{input: "1.5", expected: FloatNumber},
+		{input: "1e10", expected: FloatNumber},
+		{input: "1+2i", expected: ComplexNumber},
+	}
+	for _, test := range tests {
+		n := NewNumber(test.input, UnknownSource)
+		if NumberTypeOf(n) != test.expected {
+			t.Errorf("NewNumber(%q): expected %s, but got %s", test.input, test.expected, NumberTypeOf(n))
+		}
+		// numbers implemented elsewhere are classified by their text
+		if got := NumberTypeOf(plainNumber{n}); got != test.expected {
+			t.Errorf("plain %q: expected %s, but got %s", test.input, test.expected, got)
+		}
+	}
+	if i, err := BigIntOf(plainNumber{NewNumber("123456789012345678901234567890", UnknownSource)}); err != nil || i.String() != "123456789012345678901234567890" {
+		t.Errorf("unexpected big integer %v (%v)", i, err)
+	}
+}
+
+// plainNumber hides the ClassifiedNumber methods of a Number.
+type plainNumber struct {
+	Number
+}
+
+func TestCompareNumbers(t *testing.T) {
+	tests := []struct {
+		left, right string
+		expected    int
+	}{
+		{left: "9007199254740993", right: "9007199254740992", expected: 1},
+		{left: "123456789012345678901234567891", right: "123456789012345678901234567890", expected: 1},
+		{left: "0.1", right: "0.10", expected: 0},
+		{left: "2", right: "2.5", expected: -1},
+		{left: "1+2i", right: "1+3i", expected: -1},
+	}
+	for _, test := range tests {
+		left := NewNumber(test.left, UnknownSource)
+		right := NewNumber(test.right, UnknownSource)
+		result, err := left.CompareTo(right)
+		if err != nil {
+			t.Errorf("Comparing %s to %s: unexpected error %s", test.left, test.right, err)
+		} else if result != test.expected {
+			t.Errorf("Comparing %s to %s: expected %d, but got %d", test.left, test.right, test.expected, result)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	a, err := ParseDecimal("0.1", UnknownSource)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	b, err := ParseDecimal("0.2", UnknownSource)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sum, err := a.Add(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if sum.String() != "0.3" {
+		t.Errorf("expected 0.3, but got %s", sum)
+	}
+	if r, _ := sum.CompareTo(NewNumber("0.3", UnknownSource)); r != 0 {
+		t.Errorf("expected 0.3 to compare equal, but got %d", r)
+	}
+
+	price, _ := ParseDecimal("19.99", UnknownSource)
+	total, err := price.Mul(NewInt(3, UnknownSource))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if total.String() != "59.97" {
+		t.Errorf("expected 59.97, but got %s", total)
+	}
+	share, err := total.Quo(NewInt(7, UnknownSource), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if share.String() != "8.57" {
+		t.Errorf("expected 8.57, but got %s", share)
+	}
+	if _, err := ParseDecimal("1/3", UnknownSource); err == nil {
+		t.Errorf("expected error parsing 1/3")
+	}
+}
+
+func TestTemporalKinds(t *testing.T) {
+	earlier := NewTimestamp(time.Date(2024, 8, 11, 3, 50, 4, 0, time.UTC), UnknownSource)
+	later := NewTimestamp(time.Date(2024, 8, 12, 0, 0, 0, 0, time.UTC), UnknownSource)
+	if r, err := earlier.CompareTo(later); err != nil || r != -1 {
+		t.Errorf("expected -1, but got %d (%v)", r, err)
+	}
+	if earlier.String() != "2024-08-11T03:50:04Z" {
+		t.Errorf("unexpected timestamp string %q", earlier.String())
+	}
+	if r, err := NewDuration(time.Minute, UnknownSource).CompareTo(NewDuration(time.Second, UnknownSource)); err != nil || r != 1 {
+		t.Errorf("expected 1, but got %d (%v)", r, err)
+	}
+	blob := NewBytes([]byte("hello"), UnknownSource)
+	if blob.String() != "aGVsbG8=" {
+		t.Errorf("unexpected bytes string %q", blob.String())
+	}
+	if _, err := blob.CompareTo(earlier); err == nil {
+		t.Errorf("expected error comparing bytes to timestamp")
+	}
+}
+
+func TestSpanExcerpt(t *testing.T) {
+	content := "spec:\n  ports:\n  - port: eighty\n"
+	file := NewSourceFile("service.yaml", []byte(content))
+	start := strings.Index(content, "eighty")
+	span := file.Span(start, start+len("eighty"))
+	if span.String() != "service.yaml [Ln=3,Col=11-Ln=3,Col=17]" {
+		t.Errorf("unexpected span %q", span.String())
+	}
+	if offset := file.Offset(span.From); offset != start {
+		t.Errorf("expected offset %d, but got %d", start, offset)
+	}
+
+	sb := strings.Builder{}
+	if err := RenderExcerpt(&sb, span, "expected a number"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "service.yaml:3:11: expected a number\n" +
+		"3 |   - port: eighty\n" +
+		"  |           ^^^^^^\n"
+	if sb.String() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, sb.String())
+	}
+
+	sb.Reset()
+	RenderExcerpt(&sb, UnknownSource, "expected a number")
+	if sb.String() != "<unknown>: expected a number\n" {
+		t.Errorf("unexpected output %q", sb.String())
+	}
+
+	sb.Reset()
+	if err := RenderExcerpt(&sb, &wrappedSource{span}, "expected a number"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if sb.String() != expected {
+		t.Errorf("expected wrapped span to be unwrapped, but got\n%s", sb.String())
+	}
+}
+
+type wrappedSource struct {
+	Source
+}
+
+func (s *wrappedSource) Unwrap() Source {
+	return s.Source
+}
+
+func TestProvenance(t *testing.T) {
+	defaults := NewString("info", testSource("defaults.yaml:12"))
+	prod, err := Derive(defaults, OverriddenBy, testSource("prod.yaml:4"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	set, err := Derive(prod, Via, testSource("--set"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := set.Source().String(); s != "defaults.yaml:12 overridden by prod.yaml:4 via --set" {
+		t.Errorf("unexpected provenance %q", s)
+	}
+	if s := set.(String).String(); s != "info" {
+		t.Errorf("expected value to be unchanged, but got %q", s)
+	}
+	origins := ProvenanceOf(set.Source()).Origins()
+	if len(origins) != 3 || origins[1].Action != OverriddenBy {
+		t.Errorf("unexpected origins %v", origins)
+	}
+	if defaults.Source().String() != "defaults.yaml:12" {
+		t.Errorf("expected original source to be unchanged")
+	}
+}
+
+func TestKeyedMap(t *testing.T) {
+	m, err := NewKeyedMap([]key.Interface{key.Value[int]{X: 443}}, []Value{NewString("tls", UnknownSource)}, UnknownSource)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := m.Field(key.Value[int]{X: 443}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := m.(ModifiableMap).SetField(key.Value[string]{X: "443"}, NewString("https", UnknownSource)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var keys []key.Interface
+	m.ForEach(func(k key.Interface, v Value) error {
+		keys = append(keys, k)
+		return nil
+	})
+	if len(keys) != 1 || keys[0] != (key.Value[string]{X: "443"}) {
+		t.Errorf("expected the field to be keyed by name, but got %v", keys)
+	}
+	if _, err := m.Field(key.Value[int]{X: 443}); err == nil {
+		t.Errorf("expected the int key to be replaced")
+	}
+	if v, err := m.Field(key.Value[string]{X: "443"}); err != nil || v.(String).String() != "https" {
+		t.Errorf("expected https, but got %v (%v)", v, err)
+	}
+}
+
+type testSource string
+
+func (s testSource) String() string {
+	return string(s)
+}